@@ -0,0 +1,75 @@
+// backfill 沿列表页翻页回溯抓取一个来源的历史文章，直到指定日期，用于填充新添加的来源。
+//
+// 来源需要在配置中设置 pagination（下一页链接选择器或页码地址模板）。
+// 每抓完一页都会把进度保存到存储中，中断（包括 Ctrl-C）后再次运行会从下一页继续：
+//
+//	SCRAPER_CONFIG=config/sources.yaml MONGO_URI=mongodb://localhost:27017 go run ./cmd/backfill -source GameSpot -since 2024-01-01
+//
+// 回溯的文章早于 ARTICLE_RETENTION 时会在服务的定期清理中被删除，需要相应调大保留时长。
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"game-news/scraper"
+	"game-news/storage"
+)
+
+func main() {
+	source := flag.String("source", "", "要回溯抓取的来源名称")
+	since := flag.String("since", "", "回溯到该日期（YYYY-MM-DD）为止")
+	days := flag.Int("days", 90, "未指定 -since 时回溯的天数")
+	maxPages := flag.Int("max-pages", storage.DefaultBackfillMaxPages, "本次最多抓取的列表页数")
+	restart := flag.Bool("restart", false, "丢弃已保存的进度，从第一页重新开始")
+	flag.Parse()
+
+	if *source == "" {
+		log.Fatal("-source is required")
+	}
+
+	until := time.Now().AddDate(0, 0, -*days)
+	if *since != "" {
+		parsed, err := time.Parse("2006-01-02", *since)
+		if err != nil {
+			log.Fatalf("Invalid -since: %v", err)
+		}
+		until = parsed
+	}
+
+	// 收到 SIGINT 或 SIGTERM 时中止当前页，已保存的进度不受影响
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	scraperInstance := scraper.NewScraper()
+	store := storage.NewStorage()
+
+	// 内存存储在进程退出时丢失，回溯抓取的文章和进度都不会保留
+	if store.InMemory() {
+		log.Fatal("Backfill needs MongoDB: set MONGO_URI to a reachable server")
+	}
+
+	if *restart {
+		if err := store.DeleteBackfill(*source); err != nil {
+			log.Fatalf("Failed to reset backfill progress: %v", err)
+		}
+	}
+
+	state, err := store.Backfill(ctx, scraperInstance, *source, until, *maxPages)
+	if err != nil {
+		log.Fatalf("Backfill stopped on page %d, run again to resume: %v", state.Page, err)
+	}
+
+	if state.Done() {
+		log.Printf("Backfill of %s finished: %d pages, %d articles, oldest %s",
+			*source, state.Pages, state.Articles, state.Oldest.Format("2006-01-02"))
+	} else {
+		log.Printf("Backfill of %s paused after %d pages (oldest %s), run again to continue",
+			*source, state.Pages, state.Oldest.Format("2006-01-02"))
+	}
+}
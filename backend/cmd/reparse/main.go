@@ -0,0 +1,36 @@
+// reparse 对已归档的列表页与详情页快照重新运行列表解析、正文与元信息提取，并更新存储中的文章。
+//
+// 改进选择器或提取算法之后，用它回填已有文章而无需再次访问站点：
+//
+//	SCRAPER_CONFIG=config/sources.yaml MONGO_URI=mongodb://localhost:27017 go run ./cmd/reparse -source IGN
+//
+// 需要配置 archive_dir，且文章是在开启归档后抓取的。
+package main
+
+import (
+	"flag"
+	"log"
+
+	"game-news/scraper"
+	"game-news/storage"
+)
+
+func main() {
+	source := flag.String("source", "", "只重新解析该来源的文章，为空时处理全部来源")
+	flag.Parse()
+
+	scraperInstance := scraper.NewScraper()
+	store := storage.NewStorage()
+
+	// 内存存储在启动时为空，没有可以重新解析的文章
+	if store.InMemory() {
+		log.Fatal("Reparse needs MongoDB: set MONGO_URI to a reachable server")
+	}
+
+	updated, err := store.ReparseArticles(scraperInstance, *source)
+	if err != nil {
+		log.Fatalf("Reparse failed: %v", err)
+	}
+
+	log.Printf("Reparsed %d articles", updated)
+}
@@ -0,0 +1,93 @@
+package imageproxy
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 磁盘缓存的默认上限
+const (
+	// DefaultCacheBytes 是缓存目录的总大小上限
+	DefaultCacheBytes = 1 << 30
+
+	// DefaultCacheMaxAge 是缓存的图片在没有被访问的情况下保留的时长
+	DefaultCacheMaxAge = 30 * 24 * time.Hour
+)
+
+// SetCacheLimits 设置缓存目录的总大小上限与未访问图片的保留时长，不大于0的值使用默认值
+func (p *Proxy) SetCacheLimits(maxBytes int64, maxAge time.Duration) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultCacheBytes
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultCacheMaxAge
+	}
+	p.maxCacheBytes = maxBytes
+	p.maxCacheAge = maxAge
+}
+
+// cachedFile 是缓存目录中的一个文件
+type cachedFile struct {
+	path       string
+	size       int64
+	accessedAt time.Time
+}
+
+// Prune 清理磁盘缓存，返回删除的文件数
+//
+// 先删除超过保留时长没有被访问的图片（命中缓存时会更新文件的修改时间），
+// 总大小仍超过上限时再从最久没有访问的开始删除。
+func (p *Proxy) Prune() (int, error) {
+	cutoff := time.Now().Add(-p.maxCacheAge)
+	removed := 0
+
+	files := make([]cachedFile, 0)
+	var total int64
+	err := filepath.WalkDir(p.dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		// 写入中断留下的临时文件与过期的图片
+		if info.ModTime().Before(cutoff) || (strings.Contains(d.Name(), ".tmp") && info.ModTime().Before(time.Now().Add(-time.Hour))) {
+			if os.Remove(path) == nil {
+				removed++
+			}
+			return nil
+		}
+
+		files = append(files, cachedFile{path: path, size: info.Size(), accessedAt: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return removed, err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].accessedAt.Before(files[j].accessedAt)
+	})
+	for _, file := range files {
+		if total <= p.maxCacheBytes {
+			break
+		}
+		if os.Remove(file.path) == nil {
+			total -= file.size
+			removed++
+		}
+	}
+	return removed, nil
+}
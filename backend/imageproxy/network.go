@@ -0,0 +1,105 @@
+package imageproxy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// maxRedirects 是抓取一张图片时允许跟随的重定向次数
+const maxRedirects = 5
+
+// ErrForbiddenAddress 表示图片地址指向本机、内网或其他非公网地址
+//
+// 图片地址来自抓取的页面和订阅源，任何能控制这些内容的人都可能借代理访问内网服务。
+var ErrForbiddenAddress = errors.New("image address is not a public address")
+
+// reservedPrefixes 是 netip.Addr 的方法没有覆盖、但同样不应访问的地址段
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // 本网络
+	netip.MustParsePrefix("100.64.0.0/10"),  // 运营商级 NAT
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF 协议分配
+	netip.MustParsePrefix("198.18.0.0/15"),  // 网络基准测试
+	netip.MustParsePrefix("240.0.0.0/4"),    // 保留
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64，可映射到内网 IPv4
+	netip.MustParsePrefix("64:ff9b:1::/48"), // 本地 NAT64
+	netip.MustParsePrefix("2001:db8::/32"),  // 文档示例
+	netip.MustParsePrefix("2002::/16"),      // 6to4，可映射到内网 IPv4
+	netip.MustParsePrefix("2001::/32"),      // Teredo
+	netip.MustParsePrefix("100::/64"),       // 丢弃
+	netip.MustParsePrefix("fec0::/10"),      // 已废弃的站点本地地址
+}
+
+// publicAddr 判断地址是否是可以访问的公网地址
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// dialControl 在域名解析之后、建立连接之前检查目标地址，
+// 这样解析到内网地址的域名和 DNS 重绑定都会被拦下
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !publicAddr(addr) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	return nil
+}
+
+// checkURL 检查图片地址的协议，IP 形式的主机名直接检查是否是公网地址，
+// 域名在连接时由 dialControl 检查
+func checkURL(u *url.URL) error {
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("invalid image url %q", u.String())
+	}
+	if addr, err := netip.ParseAddr(u.Hostname()); err == nil && !publicAddr(addr) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, u.Hostname())
+	}
+	return nil
+}
+
+// checkRedirect 限制重定向次数，并对重定向目标做与原地址相同的检查
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	return checkURL(req.URL)
+}
+
+// newClient 创建只访问公网地址的 HTTP 客户端
+//
+// 不使用环境变量中的代理，否则实际连接的是代理地址，检查不到图片所在的主机。
+func newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   dialControl,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:       15 * time.Second,
+		Transport:     transport,
+		CheckRedirect: checkRedirect,
+	}
+}
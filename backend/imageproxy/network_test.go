@@ -0,0 +1,183 @@
+package imageproxy
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"fd00::1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"100.64.0.1", false},
+		{"224.0.0.1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"64:ff9b::a00:1", false},
+		{"2002:a00:1::", false},
+	}
+
+	for _, tt := range tests {
+		if got := publicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("publicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestDialControl(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", true},
+		{"127.0.0.1:80", false},
+		{"[::1]:80", false},
+		{"10.0.0.1:80", false},
+		{"169.254.169.254:80", false},
+	}
+
+	for _, tt := range tests {
+		err := dialControl("tcp", tt.address, nil)
+		if tt.allowed && err != nil {
+			t.Errorf("dialControl(%s) error = %v", tt.address, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("dialControl(%s) error = %v, want ErrForbiddenAddress", tt.address, err)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url       string
+		forbidden bool
+		invalid   bool
+	}{
+		{url: "https://cdn.example.com/a.jpg"},
+		{url: "http://93.184.216.34/a.jpg"},
+		{url: "http://127.0.0.1/a.jpg", forbidden: true},
+		{url: "http://[::1]:8080/a.jpg", forbidden: true},
+		{url: "http://192.168.0.10/a.jpg", forbidden: true},
+		{url: "http://169.254.169.254/latest/meta-data", forbidden: true},
+		{url: "ftp://cdn.example.com/a.jpg", invalid: true},
+		{url: "file:///etc/passwd", invalid: true},
+		{url: "/relative/a.jpg", invalid: true},
+	}
+
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = checkURL(u)
+		switch {
+		case tt.forbidden:
+			if !errors.Is(err, ErrForbiddenAddress) {
+				t.Errorf("checkURL(%s) error = %v, want ErrForbiddenAddress", tt.url, err)
+			}
+		case tt.invalid:
+			if err == nil || errors.Is(err, ErrForbiddenAddress) {
+				t.Errorf("checkURL(%s) error = %v, want an invalid url error", tt.url, err)
+			}
+		case err != nil:
+			t.Errorf("checkURL(%s) error = %v", tt.url, err)
+		}
+	}
+}
+
+func TestCheckRedirect(t *testing.T) {
+	redirect := func(target string, hops int) error {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		return checkRedirect(req, make([]*http.Request, hops))
+	}
+
+	if err := redirect("https://cdn.example.com/a.jpg", 1); err != nil {
+		t.Errorf("redirect to a public host error = %v", err)
+	}
+	for _, target := range []string{"http://127.0.0.1/a.jpg", "http://10.0.0.1/a.jpg", "http://169.254.169.254/latest/meta-data"} {
+		if err := redirect(target, 1); !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("redirect to %s error = %v, want ErrForbiddenAddress", target, err)
+		}
+	}
+	if err := redirect("https://cdn.example.com/a.jpg", maxRedirects); err == nil {
+		t.Errorf("redirect after %d hops was followed", maxRedirects)
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "http://proxy.internal:3128")
+	t.Setenv("HTTPS_PROXY", "http://proxy.internal:3128")
+
+	client := newClient()
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("client transport is %T", client.Transport)
+	}
+	// 走代理时实际连接的是代理，检查不到图片所在的主机
+	if transport.Proxy != nil {
+		t.Error("client uses the proxy from the environment")
+	}
+	if client.CheckRedirect == nil {
+		t.Error("client follows redirects without checking them")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("request to a loopback server reached %s", r.URL)
+	}))
+	defer server.Close()
+
+	// 域名解析到本机地址时在连接前被拦下
+	_, port, _ := strings.Cut(strings.TrimPrefix(server.URL, "http://"), ":")
+	resp, err := client.Get("http://localhost:" + port + "/a.jpg")
+	if err == nil {
+		resp.Body.Close()
+	}
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("GET localhost error = %v, want ErrForbiddenAddress", err)
+	}
+}
+
+func TestRedirectToPrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/loopback":
+			http.Redirect(w, r, "http://127.0.0.1:1/a.jpg", http.StatusFound)
+		case "/private":
+			http.Redirect(w, r, "http://192.168.1.1/a.jpg", http.StatusFound)
+		case "/link-local":
+			http.Redirect(w, r, "http://169.254.169.254/latest/meta-data", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	// 测试服务器本身在本机，这里只检查重定向，不检查连接地址
+	client := &http.Client{CheckRedirect: checkRedirect}
+	for _, path := range []string{"/loopback", "/private", "/link-local"} {
+		resp, err := client.Get(server.URL + path)
+		if err == nil {
+			resp.Body.Close()
+		}
+		if !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("GET %s error = %v, want ErrForbiddenAddress", path, err)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+// Package imageproxy 抓取、校验、缩放并在磁盘上缓存文章图片，
+// 前端通过它加载图片，不再直接盗链新闻站点的图片地址。
+package imageproxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 允许的输出宽度，请求的宽度会向上取到最近的一档，避免为任意尺寸各缓存一份
+var Widths = []int{320, 640, 1200}
+
+// DefaultWidth 是未指定宽度时的输出宽度
+const DefaultWidth = 640
+
+// DefaultMaxBytes 是允许下载的原图大小上限
+const DefaultMaxBytes = 10 << 20
+
+// maxPixels 限制原图像素数，防止解码超大图片耗尽内存
+const maxPixels = 50_000_000
+
+var (
+	// ErrNotImage 表示地址返回的内容不是图片
+	ErrNotImage = errors.New("not an image")
+
+	// ErrTooLarge 表示图片超过大小或像素上限
+	ErrTooLarge = errors.New("image too large")
+)
+
+// Image 是处理后的图片
+type Image struct {
+	Data        []byte
+	ContentType string
+}
+
+// Proxy 是带磁盘缓存的图片代理
+//
+// 只访问公网地址，缓存目录的大小由 Prune 控制。
+type Proxy struct {
+	dir       string
+	client    *http.Client
+	userAgent string
+	maxBytes  int64
+
+	maxCacheBytes int64
+	maxCacheAge   time.Duration
+}
+
+// New 创建图片代理，处理后的图片缓存在 dir 中
+func New(dir, userAgent string) *Proxy {
+	return &Proxy{
+		dir:           dir,
+		client:        newClient(),
+		userAgent:     userAgent,
+		maxBytes:      DefaultMaxBytes,
+		maxCacheBytes: DefaultCacheBytes,
+		maxCacheAge:   DefaultCacheMaxAge,
+	}
+}
+
+// Get 返回缩放到指定宽度的图片，优先使用磁盘缓存
+//
+// referer 是图片所在的文章页面，部分站点只允许带本站 Referer 的图片请求。
+// 无法解码的格式（如 WebP）在校验是图片后原样返回。
+func (p *Proxy) Get(ctx context.Context, src, referer string, width int) (Image, error) {
+	width = normalizeWidth(width)
+	path := p.cachePath(src, width)
+
+	if data, err := os.ReadFile(path); err == nil {
+		// 记录访问时间，Prune 优先删除最久没有访问的图片
+		now := time.Now()
+		os.Chtimes(path, now, now)
+		return Image{Data: data, ContentType: http.DetectContentType(data)}, nil
+	}
+
+	original, err := p.fetch(ctx, src, referer)
+	if err != nil {
+		return Image{}, err
+	}
+
+	img, err := resize(original, width)
+	if err != nil {
+		return Image{}, err
+	}
+
+	// 缓存失败不影响本次返回
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err == nil {
+		writeFileAtomic(path, img.Data)
+	}
+
+	return img, nil
+}
+
+// normalizeWidth 将宽度向上取到允许的档位
+func normalizeWidth(width int) int {
+	if width <= 0 {
+		return DefaultWidth
+	}
+	for _, allowed := range Widths {
+		if width <= allowed {
+			return allowed
+		}
+	}
+	return Widths[len(Widths)-1]
+}
+
+func (p *Proxy) cachePath(src string, width int) string {
+	key := fmt.Sprintf("%x", sha256.Sum256([]byte(fmt.Sprintf("%s|%d", src, width))))
+	return filepath.Join(p.dir, key[:2], key)
+}
+
+// fetch 下载原图并校验内容确实是图片
+func (p *Proxy) fetch(ctx context.Context, src, referer string) ([]byte, error) {
+	u, err := url.Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image url %q", src)
+	}
+	if err := checkURL(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "image/*")
+	if p.userAgent != "" {
+		req.Header.Set("User-Agent", p.userAgent)
+	}
+	if referer != "" {
+		req.Header.Set("Referer", referer)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch %s: %s", src, resp.Status)
+	}
+	if resp.ContentLength > p.maxBytes {
+		return nil, ErrTooLarge
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, p.maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > p.maxBytes {
+		return nil, ErrTooLarge
+	}
+
+	// 以内容嗅探为准，服务器声明的 Content-Type 经常不可靠
+	if !strings.HasPrefix(http.DetectContentType(data), "image/") {
+		return nil, ErrNotImage
+	}
+	return data, nil
+}
+
+// resize 将图片缩小到指定宽度，原图更窄时原样返回
+func resize(data []byte, width int) (Image, error) {
+	contentType := http.DetectContentType(data)
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			// 标准库不支持的格式原样返回
+			return Image{Data: data, ContentType: contentType}, nil
+		}
+		return Image{}, ErrNotImage
+	}
+	if config.Width*config.Height > maxPixels {
+		return Image{}, ErrTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, ErrNotImage
+	}
+
+	// 原图不比目标宽时不需要重新编码
+	if src.Bounds().Dx() <= width {
+		return Image{Data: data, ContentType: contentType}, nil
+	}
+	dst := scaleDown(src, width)
+
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+		contentType = "image/jpeg"
+	} else {
+		// PNG 与 GIF 可能有透明通道，统一输出为 PNG
+		err = png.Encode(&buf, dst)
+		contentType = "image/png"
+	}
+	if err != nil {
+		return Image{}, err
+	}
+
+	return Image{Data: buf.Bytes(), ContentType: contentType}, nil
+}
+
+// scaleDown 按区域平均缩小图片，保持宽高比
+func scaleDown(src image.Image, width int) image.Image {
+	bounds := src.Bounds()
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := bounds.Min.Y + (y+1)*bounds.Dy()/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := bounds.Min.X + (x+1)*bounds.Dx()/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+
+			offset := dst.PixOffset(x, y)
+			dst.Pix[offset] = uint8(r / n >> 8)
+			dst.Pix[offset+1] = uint8(g / n >> 8)
+			dst.Pix[offset+2] = uint8(b / n >> 8)
+			dst.Pix[offset+3] = uint8(a / n >> 8)
+		}
+	}
+	return dst
+}
+
+// writeFileAtomic 先写临时文件再重命名，避免并发请求读到写了一半的缓存
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
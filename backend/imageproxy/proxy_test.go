@@ -0,0 +1,199 @@
+package imageproxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// pngImage 返回指定尺寸的 PNG 图片
+func pngImage(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// newLocalProxy 创建可以访问本机测试服务器的代理
+//
+// 生产环境的客户端拒绝本机地址，测试服务器只能用不检查连接地址的客户端访问；
+// 地址用 localhost 形式绕过 checkURL 对 IP 的检查。
+func newLocalProxy(t *testing.T, handler http.HandlerFunc) (*Proxy, string) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	p := New(t.TempDir(), "test-agent")
+	p.client = &http.Client{CheckRedirect: checkRedirect}
+	return p, strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+}
+
+func TestNormalizeWidth(t *testing.T) {
+	tests := []struct {
+		width int
+		want  int
+	}{
+		{0, DefaultWidth},
+		{-1, DefaultWidth},
+		{1, 320},
+		{320, 320},
+		{321, 640},
+		{800, 1200},
+		{5000, 1200},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeWidth(tt.width); got != tt.want {
+			t.Errorf("normalizeWidth(%d) = %d, want %d", tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestGetRejectsLocalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("request to a loopback server reached %s", r.URL)
+	}))
+	defer server.Close()
+
+	p := New(t.TempDir(), "test-agent")
+	for _, src := range []string{server.URL + "/a.png", "http://10.0.0.1/a.png", "http://169.254.169.254/a.png"} {
+		if _, err := p.Get(context.Background(), src, "", 0); !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("Get(%s) error = %v, want ErrForbiddenAddress", src, err)
+		}
+	}
+}
+
+func TestGetResizesAndCaches(t *testing.T) {
+	original := pngImage(t, 1000, 500)
+	var requests int32
+	var referer, userAgent string
+	p, base := newLocalProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		referer, userAgent = r.Header.Get("Referer"), r.Header.Get("User-Agent")
+		w.Write(original)
+	})
+
+	img, err := p.Get(context.Background(), base+"/a.png", "https://www.example.com/news/zelda", 500)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if referer != "https://www.example.com/news/zelda" || userAgent != "test-agent" {
+		t.Errorf("request headers = Referer %q, User-Agent %q", referer, userAgent)
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(img.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "png" || img.ContentType != "image/png" || config.Width != 640 || config.Height != 320 {
+		t.Errorf("resized image = %s %dx%d (%s), want png 640x320", format, config.Width, config.Height, img.ContentType)
+	}
+
+	// 同一档位的宽度命中缓存
+	if _, err := p.Get(context.Background(), base+"/a.png", "", 600); err != nil {
+		t.Fatalf("cached Get() error = %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("cached image was fetched %d times", requests)
+	}
+
+	// 原图比目标窄时原样返回
+	img, err = p.Get(context.Background(), base+"/a.png", "", 1200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(img.Data, original) {
+		t.Error("narrower original was re-encoded")
+	}
+}
+
+func TestGetRejectsInvalidImages(t *testing.T) {
+	p, base := newLocalProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("<html><body>not an image</body></html>"))
+		case "/large.png":
+			w.Write(pngImage(t, 200, 200))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	if _, err := p.Get(context.Background(), base+"/page.png", "", 0); !errors.Is(err, ErrNotImage) {
+		t.Errorf("Get() of an html page error = %v, want ErrNotImage", err)
+	}
+	if _, err := p.Get(context.Background(), base+"/missing.png", "", 0); err == nil {
+		t.Error("Get() of a missing image succeeded")
+	}
+
+	p.maxBytes = 100
+	if _, err := p.Get(context.Background(), base+"/large.png", "", 0); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Get() of an oversized image error = %v, want ErrTooLarge", err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	p := New(t.TempDir(), "test-agent")
+	p.SetCacheLimits(250, time.Hour)
+
+	write := func(name string, size int, accessed time.Time) string {
+		path := filepath.Join(p.dir, name[:2], name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, accessed, accessed)
+		return path
+	}
+
+	now := time.Now()
+	expired := write("aaexpired", 10, now.Add(-2*time.Hour))
+	tmp := write("bbimage.tmp123", 10, now.Add(-90*time.Minute))
+	recentTmp := write("bbimage.tmp456", 10, now.Add(-time.Minute))
+	oldest := write("ccoldest", 100, now.Add(-30*time.Minute))
+	older := write("ddolder", 100, now.Add(-20*time.Minute))
+	newest := write("eenewest", 100, now.Add(-10*time.Minute))
+
+	removed, err := p.Prune()
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	// 过期的图片与中断的临时文件先删除，之后从最久没有访问的开始删除直到不超过上限
+	if removed != 3 {
+		t.Errorf("Prune() removed %d files, want 3", removed)
+	}
+	for _, path := range []string{expired, tmp, oldest} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed", filepath.Base(path))
+		}
+	}
+	for _, path := range []string{recentTmp, older, newest} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s was removed", filepath.Base(path))
+		}
+	}
+
+	// 缓存目录不存在时没有可清理的文件
+	p.dir = filepath.Join(p.dir, "missing")
+	if removed, err := p.Prune(); removed != 0 || err != nil {
+		t.Errorf("Prune() of a missing directory = %d, %v", removed, err)
+	}
+}
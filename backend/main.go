@@ -1,9 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"log"
 	"net/http"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 	"os"
+	"game-news/imageproxy"
 	"game-news/scraper"
 	"game-news/storage"
 	"github.com/gin-gonic/gin"
@@ -13,14 +23,38 @@ import (
 
 // News 结构体定义新闻数据结构，用于API响应
 type News struct {
-	ID      string `json:"id"`
-	Title   string `json:"title"`
-	Summary string `json:"summary"`
-	Content string `json:"content"`
-	Image   string `json:"image"`
-	Source  string `json:"source"`
-	Date    string `json:"date"`
-	URL     string `json:"url"`
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Summary  string `json:"summary"`
+	Content  string `json:"content"`
+	Image    string `json:"image"`
+	Source   string `json:"source"`
+	Author   string `json:"author,omitempty"`
+	Date     string `json:"date"`
+	URL      string `json:"url"`
+	Language string `json:"language,omitempty"`
+	
+	// AlsoCoveredBy 列出其它来源对同一新闻的报道
+	AlsoCoveredBy []Coverage `json:"also_covered_by,omitempty"`
+}
+
+// Coverage 其它来源对同一新闻的报道
+type Coverage struct {
+	ID     string `json:"id"`
+	Source string `json:"source"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+}
+
+// Story 多个来源对同一事件的报道组成的新闻故事
+type Story struct {
+	ID               string    `json:"id"`
+	Headline         string    `json:"headline"`
+	Sources          []string  `json:"sources"`
+	ArticleCount     int       `json:"article_count"`
+	FirstPublishedAt time.Time `json:"first_published_at"`
+	LastPublishedAt  time.Time `json:"last_published_at"`
+	Articles         []News    `json:"articles"`
 }
 
 // User 结构体定义用户数据结构
@@ -34,29 +68,134 @@ type BookmarkRequest struct {
 	ArticleID string `json:"article_id"`
 }
 
+// ScrapeRunReport 单个来源一次抓取的运行报告
+type ScrapeRunReport struct {
+	RunID       string         `json:"run_id"`
+	StartedAt   time.Time      `json:"started_at"`
+	DurationMs  int64          `json:"duration_ms"`
+	URLsVisited []string       `json:"urls_visited"`
+	Statuses    map[string]int `json:"statuses"`
+	ItemsFound  int            `json:"items_found"`
+	ItemsParsed int            `json:"items_parsed"`
+	FieldCounts map[string]int `json:"field_counts"`
+	Incremental bool           `json:"incremental"`
+	Unchanged   int            `json:"unchanged"`
+	Errors      []string       `json:"errors"`
+	Snapshots   []string       `json:"snapshots,omitempty"`
+	Stored      bool           `json:"stored"`
+}
+
+// SourceHealth 来源的健康状态、熔断器状态及最近的运行报告
+type SourceHealth struct {
+	Source  string            `json:"source"`
+	Status  string            `json:"status"`
+	Breaker *BreakerStatus    `json:"breaker,omitempty"`
+	Runs    []ScrapeRunReport `json:"runs"`
+}
+
+// BreakerStatus 来源熔断器的当前状态
+type BreakerStatus struct {
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	RetryAt             *time.Time `json:"retry_at,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+}
+
+// SourceEvent 来源退化事件
+type SourceEvent struct {
+	Type       string    `json:"type"`
+	Source     string    `json:"source"`
+	RunID      string    `json:"run_id"`
+	DetectedAt time.Time `json:"detected_at"`
+	Reasons    []string  `json:"reasons"`
+}
+
+// driftHistoryRuns 是漂移检测时参考的历史运行次数
+const driftHistoryRuns = 20
+
+// shutdownTimeout 是退出时等待进行中的HTTP请求完成的时长
+const shutdownTimeout = 10 * time.Second
+
 func main() {
+	// 收到 SIGINT 或 SIGTERM 时取消 ctx，中止进行中的抓取并关闭服务器
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	
 	// 创建存储实例
 	store := storage.NewStorage()
 	
+	// 将旧的8位文章ID迁移为新ID，旧ID保留为别名，已有的书签与链接仍然有效
+	if migrated, err := store.MigrateArticleIDs(); err != nil {
+		log.Printf("Failed to migrate article IDs: %v", err)
+	} else if migrated > 0 {
+		log.Printf("Migrated %d articles to new IDs", migrated)
+	}
+	
+	// 为加入语言检测之前保存的文章补充语言
+	if detected, err := store.DetectLanguages(); err != nil {
+		log.Printf("Failed to detect article languages: %v", err)
+	} else if detected > 0 {
+		log.Printf("Detected language of %d articles", detected)
+	}
+	
+	// 图片代理，处理后的图片缓存在 IMAGE_CACHE_DIR 中
+	imageCacheDir := os.Getenv("IMAGE_CACHE_DIR")
+	if imageCacheDir == "" {
+		imageCacheDir = "cache/images"
+	}
+	images := imageproxy.New(imageCacheDir, scraper.DefaultUserAgent)
+	images.SetCacheLimits(imageCacheBytes(), 0)
+	
 	// 创建爬虫实例
 	scraper := scraper.NewScraper()
 	
+	// 恢复各来源上次成功抓取的时间，sitemap 等增量来源从上次停下的地方继续
+	restoreLastRuns(store, scraper)
+	
+	// 来源退化时的告警通知
+	notifiers := newNotifiers()
+	
 	// 初始抓取新闻
-	if articles, err := scraper.ScrapeGames(); err == nil {
-		store.AddArticles(articles, scraper)
-	}
+	runScrape(ctx, store, scraper, notifiers)
+	
+	// 文章保留时长，回溯抓取了更早的文章时需要调大
+	retention := articleRetention()
 	
-	// 定期抓取新闻 (每小时一次)
+	// 定期抓取新闻 (每小时一次)，退出时等待当前一轮中止
+	var wg sync.WaitGroup
+	wg.Add(1)
 	go func() {
+		defer wg.Done()
+		
 		ticker := time.NewTicker(1 * time.Hour)
 		defer ticker.Stop()
 		
-		for range ticker.C {
-			if articles, err := scraper.ScrapeGames(); err == nil {
-				store.AddArticles(articles, scraper)
-				
-				// 清理超过7天的旧新闻
-				store.Cleanup(7 * 24 * time.Hour)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			
+			if runScrape(ctx, store, scraper, notifiers) {
+				// 清理超过保留时长的旧新闻，以及不再被文章引用的旧页面快照
+				store.Cleanup(retention)
+				pruneArchive(store, scraper, retention)
+			}
+			
+			// 清理长时间没有用到的HTTP缓存
+			if pruned, err := scraper.PruneCache(); err != nil {
+				log.Printf("Failed to prune HTTP cache: %v", err)
+			} else if pruned > 0 {
+				log.Printf("Pruned %d HTTP cache entries", pruned)
+			}
+			
+			// 图片缓存超过上限时删除最久没有访问的图片
+			if pruned, err := images.Prune(); err != nil {
+				log.Printf("Failed to prune image cache: %v", err)
+			} else if pruned > 0 {
+				log.Printf("Pruned %d cached images", pruned)
 			}
 		}
 	}()
@@ -99,7 +238,12 @@ func main() {
 			public.GET("/news", getNews(store))
 			public.GET("/news/:id", getNewsByID(store))
 			public.GET("/search", searchNews(store))
-			public.GET("/sources", getSources(store))
+			public.GET("/stories", getStories(store))
+			public.GET("/stories/:id", getStoryByID(store))
+			public.GET("/images/:id", getImage(store, images))
+			public.GET("/sources", getSources(scraper))
+			public.GET("/health/sources", getSourceHealth(store, scraper))
+			public.GET("/health/events", getSourceEvents(store))
 			public.POST("/users/register", registerUser(store))
 			public.POST("/users/login", loginUser(store))
 		}
@@ -121,7 +265,172 @@ func main() {
 	}
 	
 	// 启动服务器
-	router.Run(":" + port)
+	server := &http.Server{
+		Addr:    ":" + port,
+		Handler: router,
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed: %v", err)
+		}
+	}()
+	
+	// 等待退出信号，停止接收新请求并等待进行中的请求和抓取结束
+	<-ctx.Done()
+	stop()
+	log.Println("Shutting down...")
+	
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Failed to shut down server: %v", err)
+	}
+	wg.Wait()
+}
+
+// articleRetention 返回 ARTICLE_RETENTION 设置的文章保留时长（如 2160h），默认7天
+func articleRetention() time.Duration {
+	if value := os.Getenv("ARTICLE_RETENTION"); value != "" {
+		retention, err := time.ParseDuration(value)
+		if err == nil && retention > 0 {
+			return retention
+		}
+		log.Printf("Invalid ARTICLE_RETENTION %q, keeping articles for 7 days", value)
+	}
+	return 7 * 24 * time.Hour
+}
+
+// pruneArchive 删除超过保留时长、且不再被任何文章引用的页面快照
+func pruneArchive(store *storage.Storage, scraperInstance *scraper.Scraper, retention time.Duration) {
+	keep, err := store.SnapshotKeys()
+	if err != nil {
+		log.Printf("Failed to list referenced snapshots: %v", err)
+		return
+	}
+	
+	if pruned, err := scraperInstance.PruneArchive(time.Now().Add(-retention), keep); err != nil {
+		log.Printf("Failed to prune snapshot archive: %v", err)
+	} else if pruned > 0 {
+		log.Printf("Pruned %d page snapshots", pruned)
+	}
+}
+
+// imageCacheBytes 返回 IMAGE_CACHE_MAX_MB 设置的图片缓存大小上限，未设置时返回0，使用默认值
+func imageCacheBytes() int64 {
+	value := os.Getenv("IMAGE_CACHE_MAX_MB")
+	if value == "" {
+		return 0
+	}
+	megabytes, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || megabytes <= 0 {
+		log.Printf("Invalid IMAGE_CACHE_MAX_MB %q, using the default limit", value)
+		return 0
+	}
+	return megabytes << 20
+}
+
+// newNotifiers 根据环境变量创建告警通知，设置 ALERT_WEBHOOK_URL 时会将事件POST到该地址
+func newNotifiers() []scraper.Notifier {
+	notifiers := make([]scraper.Notifier, 0)
+	if webhookURL := os.Getenv("ALERT_WEBHOOK_URL"); webhookURL != "" {
+		notifiers = append(notifiers, scraper.NewWebhookNotifier(webhookURL))
+	}
+	return notifiers
+}
+
+// restoreLastRuns 用保存的运行报告恢复每个来源上一次文章成功入库的抓取时间
+func restoreLastRuns(store *storage.Storage, scraperInstance *scraper.Scraper) {
+	for _, src := range scraperInstance.Sources().Enabled() {
+		history, err := store.GetSourceHistory(src.Name(), driftHistoryRuns)
+		if err != nil {
+			log.Printf("Failed to load history of source %s: %v", src.Name(), err)
+			continue
+		}
+		
+		for _, report := range history {
+			if report.Stored && !report.Failed() {
+				scraperInstance.SetLastRun(src.Name(), report.StartedAt)
+				break
+			}
+		}
+	}
+}
+
+// runScrape 执行一次抓取，将文章入库并保存每个来源的运行报告，成功时返回 true
+//
+// ctx 取消时中止抓取和入库。报告在入库之后才保存，被中断的一轮不保存报告，
+// 以免不完整的结果触发退化告警或被当作成功的抓取；已经写入的文章会保留。
+func runScrape(ctx context.Context, store *storage.Storage, scraperInstance *scraper.Scraper, notifiers []scraper.Notifier) bool {
+	articles, reports, err := scraperInstance.ScrapeGamesWithReportsContext(ctx)
+	if ctx.Err() != nil {
+		log.Printf("Scrape run interrupted: %v", ctx.Err())
+		return false
+	}
+	
+	for _, report := range reports {
+		if report.Failed() {
+			log.Printf("Source %s failed: %v", report.Source, report.Errors)
+		}
+	}
+	
+	stored := false
+	if err != nil {
+		log.Printf("Scrape run failed: %v", err)
+	} else if storeErr := store.AddArticlesContext(ctx, articles, scraperInstance); storeErr != nil {
+		log.Printf("Failed to store articles: %v", storeErr)
+	} else {
+		stored = true
+	}
+	
+	if ctx.Err() != nil {
+		log.Printf("Scrape run interrupted: %v", ctx.Err())
+		return false
+	}
+	
+	// 增量来源的进度只在文章入库之后推进，入库失败时下一轮重新抓取这段时间的更新
+	if stored {
+		for i := range reports {
+			reports[i].Stored = true
+			if !reports[i].Failed() {
+				scraperInstance.SetLastRun(reports[i].Source, reports[i].StartedAt)
+			}
+		}
+	}
+	
+	// 必须在保存本次报告之前检测，历史中不能包含本次运行
+	detectDrift(store, reports, notifiers)
+	
+	if saveErr := store.SaveScrapeRuns(reports); saveErr != nil {
+		log.Printf("Failed to save scrape reports: %v", saveErr)
+	}
+	
+	return stored
+}
+
+// detectDrift 将每个来源本次的产出与历史基线比较，出现退化时记录日志、保存事件并发送通知
+func detectDrift(store *storage.Storage, reports []scraper.SourceReport, notifiers []scraper.Notifier) {
+	for _, report := range reports {
+		history, err := store.GetSourceHistory(report.Source, driftHistoryRuns)
+		if err != nil {
+			log.Printf("Failed to load history of source %s: %v", report.Source, err)
+			continue
+		}
+		
+		event, degraded := scraper.DetectDrift(report, history)
+		if !degraded {
+			continue
+		}
+		
+		log.Printf("Source %s degraded: %v", event.Source, event.Reasons)
+		if err := store.SaveSourceEvent(event); err != nil {
+			log.Printf("Failed to save source event: %v", err)
+		}
+		for _, notifier := range notifiers {
+			if err := notifier.Notify(event); err != nil {
+				log.Printf("Failed to send source degraded alert: %v", err)
+			}
+		}
+	}
 }
 
 // getNews 返回所有新闻
@@ -129,16 +438,23 @@ func getNews(store *storage.Storage) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 获取查询参数
 		source := c.Query("source")
+		language := languageParam(c)
+		
+		// 默认将不同来源对同一新闻的报道合并为一条，collapse=false 时返回全部
+		collapse := c.DefaultQuery("collapse", "true") != "false"
 		
 		var articles []storage.ArticleWithContent
 		var err error
 		
 		if source != "" {
 			// 按来源过滤
-			articles, err = store.FilterArticlesBySource(source)
+			articles, err = store.FilterArticlesBySource(source, language)
+		} else if collapse {
+			// 多取一些，合并重复报道后仍能凑够20篇
+			articles, err = store.GetRecentArticles(20*3, language)
 		} else {
 			// 获取所有文章
-			articles, err = store.GetRecentArticles(20) // 限制20篇文章
+			articles, err = store.GetRecentArticles(20, language) // 限制20篇文章
 		}
 		
 		if err != nil {
@@ -146,18 +462,33 @@ func getNews(store *storage.Storage) gin.HandlerFunc {
 			return
 		}
 		
+		if collapse {
+			articles = collapseDuplicates(articles)
+			if source == "" && len(articles) > 20 {
+				articles = articles[:20]
+			}
+		}
+		
 		// 转换为API响应格式
 		newsList := make([]News, len(articles))
 		for i, article := range articles {
 			newsList[i] = News{
-				ID:      article.ID,
-				Title:   article.Title,
-				Summary: article.Summary,
-				Content: "", // 在列表中不包含完整内容以减少数据传输
-				Image:   article.ImageURL,
-				Source:  article.Source,
-				Date:    article.PublishedAt.Format("2006-01-02"),
-				URL:     article.URL,
+				ID:       article.ID,
+				Title:    article.Title,
+				Summary:  article.Summary,
+				Content:  "", // 在列表中不包含完整内容以减少数据传输
+				Image:    imageURL(article),
+				Source:   article.Source,
+				Author:   article.Author,
+				Date:     article.PublishedAt.Format("2006-01-02"),
+				URL:      article.URL,
+				Language: article.Language,
+			}
+		}
+		
+		if collapse {
+			if err := addCoverage(store, articles, newsList); err != nil {
+				log.Printf("Failed to load duplicate coverage: %v", err)
 			}
 		}
 		
@@ -165,6 +496,58 @@ func getNews(store *storage.Storage) gin.HandlerFunc {
 	}
 }
 
+// storyID 返回文章所属新闻的ID，即最早报道该新闻的文章ID
+func storyID(article storage.ArticleWithContent) string {
+	if article.DuplicateOf != "" {
+		return article.DuplicateOf
+	}
+	return article.ID
+}
+
+// collapseDuplicates 同一新闻只保留列表中最先出现的一篇，保持原有顺序
+func collapseDuplicates(articles []storage.ArticleWithContent) []storage.ArticleWithContent {
+	seen := make(map[string]bool)
+	collapsed := make([]storage.ArticleWithContent, 0, len(articles))
+	for _, article := range articles {
+		id := storyID(article)
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		collapsed = append(collapsed, article)
+	}
+	return collapsed
+}
+
+// addCoverage 为每条新闻填充其它来源的报道
+func addCoverage(store *storage.Storage, articles []storage.ArticleWithContent, newsList []News) error {
+	storyIDs := make([]string, len(articles))
+	for i, article := range articles {
+		storyIDs[i] = storyID(article)
+	}
+	
+	groups, err := store.GetDuplicateGroups(storyIDs)
+	if err != nil {
+		return err
+	}
+	
+	for i, article := range articles {
+		for _, other := range groups[storyIDs[i]] {
+			if other.ID == article.ID {
+				continue
+			}
+			newsList[i].AlsoCoveredBy = append(newsList[i].AlsoCoveredBy, Coverage{
+				ID:     other.ID,
+				Source: other.Source,
+				Title:  other.Title,
+				URL:    other.URL,
+			})
+		}
+	}
+	
+	return nil
+}
+
 // getNewsByID 根据ID返回特定新闻
 func getNewsByID(store *storage.Storage) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -183,20 +566,164 @@ func getNewsByID(store *storage.Storage) gin.HandlerFunc {
 		
 		// 转换为API响应格式
 		news := News{
-			ID:      article.ID,
-			Title:   article.Title,
-			Summary: article.Summary,
-			Content: article.Content,
-			Image:   article.ImageURL,
-			Source:  article.Source,
-			Date:    article.PublishedAt.Format("2006-01-02"),
-			URL:     article.URL,
+			ID:       article.ID,
+			Title:    article.Title,
+			Summary:  article.Summary,
+			Content:  article.Content,
+			Image:    imageURL(article),
+			Source:   article.Source,
+			Author:   article.Author,
+			Date:     article.PublishedAt.Format("2006-01-02"),
+			URL:      article.URL,
+			Language: article.Language,
 		}
 		
 		c.JSON(http.StatusOK, news)
 	}
 }
 
+// getImage 通过图片代理返回文章的配图，可用查询参数 w 指定宽度
+func getImage(store *storage.Storage, images *imageproxy.Proxy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		article, found, err := store.GetArticleByID(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch news"})
+			return
+		}
+		
+		if !found || article.ImageURL == "" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+			return
+		}
+		
+		width := 0
+		if w := c.Query("w"); w != "" {
+			width, err = strconv.Atoi(w)
+			if err != nil || width <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'w' must be a positive integer"})
+				return
+			}
+		}
+		
+		img, err := images.Get(c.Request.Context(), article.ImageURL, article.URL, width)
+		if err != nil {
+			log.Printf("Failed to proxy image %s: %v", article.ImageURL, err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch image"})
+			return
+		}
+		
+		c.Header("Cache-Control", "public, max-age=86400")
+		c.Data(http.StatusOK, img.ContentType, img.Data)
+	}
+}
+
+// languageParam 返回 lang 查询参数中的语言代码，zh-CN 这样带地区的写法只取语言部分
+func languageParam(c *gin.Context) string {
+	language := strings.ToLower(strings.TrimSpace(c.Query("lang")))
+	if i := strings.IndexAny(language, "-_"); i >= 0 {
+		language = language[:i]
+	}
+	return language
+}
+
+// imageURL 返回文章配图经图片代理的地址，前端不直接请求新闻站点的图片
+func imageURL(article storage.ArticleWithContent) string {
+	if article.ImageURL == "" {
+		return ""
+	}
+	return "/api/images/" + article.ID
+}
+
+// getStories 返回最近的新闻故事及其包含的文章
+func getStories(store *storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		if err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'limit' must be a positive integer"})
+			return
+		}
+		
+		stories, err := store.GetStories(limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stories"})
+			return
+		}
+		
+		storyIDs := make([]string, len(stories))
+		for i, story := range stories {
+			storyIDs[i] = story.ID
+		}
+		
+		members, err := store.GetStoryArticles(storyIDs)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stories"})
+			return
+		}
+		
+		response := make([]Story, len(stories))
+		for i, story := range stories {
+			response[i] = newStory(story, members[story.ID])
+		}
+		
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// getStoryByID 根据ID返回新闻故事及其包含的文章
+func getStoryByID(store *storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		
+		story, found, err := store.GetStoryByID(id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch story"})
+			return
+		}
+		
+		if !found {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Story not found"})
+			return
+		}
+		
+		members, err := store.GetStoryArticles([]string{id})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch story"})
+			return
+		}
+		
+		c.JSON(http.StatusOK, newStory(story, members[id]))
+	}
+}
+
+// newStory 转换为API响应格式，文章按发布时间从早到晚排列
+func newStory(story storage.Story, articles []storage.ArticleWithContent) Story {
+	response := Story{
+		ID:               story.ID,
+		Headline:         story.Headline,
+		Sources:          story.Sources,
+		ArticleCount:     len(articles),
+		FirstPublishedAt: story.FirstPublishedAt,
+		LastPublishedAt:  story.LastPublishedAt,
+		Articles:         make([]News, len(articles)),
+	}
+	
+	for i, article := range articles {
+		response.Articles[i] = News{
+			ID:       article.ID,
+			Title:    article.Title,
+			Summary:  article.Summary,
+			Image:    imageURL(article),
+			Source:   article.Source,
+			Author:   article.Author,
+			Date:     article.PublishedAt.Format("2006-01-02"),
+			URL:      article.URL,
+			Language: article.Language,
+		}
+	}
+	
+	return response
+}
+
 // searchNews 搜索新闻
 func searchNews(store *storage.Storage) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -206,7 +733,7 @@ func searchNews(store *storage.Storage) gin.HandlerFunc {
 			return
 		}
 		
-		articles, err := store.SearchArticles(query)
+		articles, err := store.SearchArticles(query, languageParam(c))
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search news"})
 			return
@@ -216,14 +743,16 @@ func searchNews(store *storage.Storage) gin.HandlerFunc {
 		newsList := make([]News, len(articles))
 		for i, article := range articles {
 			newsList[i] = News{
-				ID:      article.ID,
-				Title:   article.Title,
-				Summary: article.Summary,
-				Content: "", // 在列表中不包含完整内容以减少数据传输
-				Image:   article.ImageURL,
-				Source:  article.Source,
-				Date:    article.PublishedAt.Format("2006-01-02"),
-				URL:     article.URL,
+				ID:       article.ID,
+				Title:    article.Title,
+				Summary:  article.Summary,
+				Content:  "", // 在列表中不包含完整内容以减少数据传输
+				Image:    imageURL(article),
+				Source:   article.Source,
+				Author:   article.Author,
+				Date:     article.PublishedAt.Format("2006-01-02"),
+				URL:      article.URL,
+				Language: article.Language,
 			}
 		}
 		
@@ -231,15 +760,146 @@ func searchNews(store *storage.Storage) gin.HandlerFunc {
 	}
 }
 
-// getSources 获取所有新闻来源
-func getSources(store *storage.Storage) gin.HandlerFunc {
+// getSources 获取抓取器配置中所有启用的新闻来源
+func getSources(scraperInstance *scraper.Scraper) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 这里应该从数据库查询所有不同的来源
-		sources := []string{"GameSpot", "IGN", "GameNews Network", "eSports Daily", "Indie Game Watch"}
+		enabled := scraperInstance.Sources().Enabled()
+		sources := make([]string, len(enabled))
+		for i, src := range enabled {
+			sources[i] = src.Name()
+		}
 		c.JSON(http.StatusOK, sources)
 	}
 }
 
+// getSourceHealth 返回每个来源最近N次抓取的运行报告及熔断器状态
+func getSourceHealth(store *storage.Storage, scraperInstance *scraper.Scraper) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+		if err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'limit' must be a positive integer"})
+			return
+		}
+		
+		runsBySource, err := store.GetScrapeRuns(limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch source health"})
+			return
+		}
+		
+		breakers := make(map[string]*BreakerStatus)
+		for _, state := range scraperInstance.BreakerStates() {
+			breakers[state.Source] = newBreakerStatus(state)
+		}
+		
+		healthList := make([]SourceHealth, 0, len(runsBySource))
+		for source, runs := range runsBySource {
+			health := SourceHealth{
+				Source:  source,
+				Status:  sourceStatus(runs, breakers[source]),
+				Breaker: breakers[source],
+				Runs:    make([]ScrapeRunReport, len(runs)),
+			}
+			
+			for i, run := range runs {
+				health.Runs[i] = ScrapeRunReport{
+					RunID:       run.RunID,
+					StartedAt:   run.StartedAt,
+					DurationMs:  run.DurationMs,
+					URLsVisited: run.URLsVisited,
+					Statuses:    run.Statuses,
+					ItemsFound:  run.ItemsFound,
+					ItemsParsed: run.ItemsParsed,
+					FieldCounts: run.FieldCounts,
+					Incremental: run.Incremental,
+					Unchanged:   run.Unchanged,
+					Errors:      run.Errors,
+					Snapshots:   run.Snapshots,
+					Stored:      run.Stored,
+				}
+			}
+			
+			healthList = append(healthList, health)
+		}
+		
+		sort.Slice(healthList, func(i, j int) bool {
+			return healthList[i].Source < healthList[j].Source
+		})
+		
+		c.JSON(http.StatusOK, healthList)
+	}
+}
+
+// getSourceEvents 返回最近的来源退化事件，可按来源过滤
+func getSourceEvents(store *storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		if err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'limit' must be a positive integer"})
+			return
+		}
+		
+		events, err := store.GetSourceEvents(c.Query("source"), limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch source events"})
+			return
+		}
+		
+		response := make([]SourceEvent, len(events))
+		for i, event := range events {
+			response[i] = SourceEvent{
+				Type:       event.Type,
+				Source:     event.Source,
+				RunID:      event.RunID,
+				DetectedAt: event.DetectedAt,
+				Reasons:    event.Reasons,
+			}
+		}
+		
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// sourceStatus 根据熔断器和最近一次运行判断来源状态：ok、degraded（有错误但仍有产出）、failing 或 paused（熔断中）
+func sourceStatus(runs []storage.ScrapeRun, breaker *BreakerStatus) string {
+	if breaker != nil && breaker.State == scraper.BreakerOpen {
+		return "paused"
+	}
+	if len(runs) == 0 {
+		return "unknown"
+	}
+	
+	latest := runs[0]
+	switch {
+	case latest.Incremental && latest.ItemsFound > 0 && len(latest.Errors) == 0:
+		// 增量来源没有新文章时产出为零是正常的
+		return "ok"
+	case latest.Unchanged > 0 && len(latest.Errors) == 0:
+		// 列表页未变化时不再解析，产出为零是正常的
+		return "ok"
+	case latest.ItemsParsed == 0:
+		return "failing"
+	case len(latest.Errors) > 0:
+		return "degraded"
+	default:
+		return "ok"
+	}
+}
+
+// newBreakerStatus 将熔断器状态转换为API响应格式
+func newBreakerStatus(state scraper.BreakerState) *BreakerStatus {
+	status := &BreakerStatus{
+		State:               state.State,
+		ConsecutiveFailures: state.ConsecutiveFailures,
+		LastError:           state.LastError,
+	}
+	if !state.OpenedAt.IsZero() {
+		status.OpenedAt = &state.OpenedAt
+		status.RetryAt = &state.RetryAt
+	}
+	return status
+}
+
 // registerUser 用户注册
 func registerUser(store *storage.Storage) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -369,14 +1029,16 @@ func getUserBookmarks(store *storage.Storage) gin.HandlerFunc {
 		newsList := make([]News, len(articles))
 		for i, article := range articles {
 			newsList[i] = News{
-				ID:      article.ID,
-				Title:   article.Title,
-				Summary: article.Summary,
-				Content: "", // 在列表中不包含完整内容以减少数据传输
-				Image:   article.ImageURL,
-				Source:  article.Source,
-				Date:    article.PublishedAt.Format("2006-01-02"),
-				URL:     article.URL,
+				ID:       article.ID,
+				Title:    article.Title,
+				Summary:  article.Summary,
+				Content:  "", // 在列表中不包含完整内容以减少数据传输
+				Image:    imageURL(article),
+				Source:   article.Source,
+				Author:   article.Author,
+				Date:     article.PublishedAt.Format("2006-01-02"),
+				URL:      article.URL,
+				Language: article.Language,
 			}
 		}
 		
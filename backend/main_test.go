@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"game-news/scraper"
+	"game-news/storage"
+)
+
+const listingPage = `<html><body>
+	<div class="item"><a href="/news/zelda">Zelda sequel announced</a></div>
+</body></html>`
+
+const detailPage = `<html><body><article><p>Nintendo has announced that the next Legend of Zelda game will launch on Switch 2 in March next year.</p></article></body></html>`
+
+// newTestSite 提供一个列表页和一篇文章，blockPath 的请求到达时调用 cancel 并等到请求被取消
+func newTestSite(t *testing.T, blockPath string, cancel context.CancelFunc) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == blockPath {
+			cancel()
+			<-r.Context().Done()
+			return
+		}
+		switch r.URL.Path {
+		case "/news":
+			w.Write([]byte(listingPage))
+		case "/news/zelda":
+			w.Write([]byte(detailPage))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestScraper(server *httptest.Server) *scraper.Scraper {
+	return scraper.NewScraperWithConfig(&scraper.Config{
+		Politeness: scraper.PolitenessConfig{Default: scraper.DomainPolicy{Parallelism: 2}},
+		Sources: []scraper.SourceConfig{{
+			Name:       "Example",
+			ListingURL: scraper.StringList{server.URL + "/news"},
+			Selectors: scraper.SelectorConfig{
+				Item:  "div.item",
+				Title: scraper.StringList{"a"},
+				Link:  scraper.StringList{"a"},
+			},
+		}},
+	})
+}
+
+func TestRunScrape(t *testing.T) {
+	tests := []struct {
+		name      string
+		blockPath string
+		stored    bool
+	}{
+		{name: "completed run", stored: true},
+		// 抓取列表页时中断
+		{name: "interrupted while scraping", blockPath: "/news"},
+		// 入库抓取详情页时中断
+		{name: "interrupted while storing", blockPath: "/news/zelda"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MONGO_URI", "")
+			store := storage.NewStorage()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			scraperInstance := newTestScraper(newTestSite(t, tt.blockPath, cancel))
+
+			if stored := runScrape(ctx, store, scraperInstance, nil); stored != tt.stored {
+				t.Fatalf("runScrape() = %v, want %v", stored, tt.stored)
+			}
+
+			runs, err := store.GetScrapeRuns(10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.stored {
+				// 被中断的一轮不保存报告，也不推进来源的进度
+				if len(runs) != 0 {
+					t.Errorf("interrupted run saved reports: %v", runs)
+				}
+				if last := scraperInstance.LastRun("Example"); !last.IsZero() {
+					t.Errorf("interrupted run set the last run to %v", last)
+				}
+				return
+			}
+
+			if len(runs["Example"]) != 1 || !runs["Example"][0].Stored {
+				t.Errorf("saved runs = %+v", runs)
+			}
+			if scraperInstance.LastRun("Example").IsZero() {
+				t.Error("completed run did not set the last run")
+			}
+			articles, err := store.GetArticles()
+			if err != nil || len(articles) != 1 {
+				t.Errorf("stored %d articles, %v", len(articles), err)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package scraper
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// ErrNoArchive 表示没有配置 archive_dir，无法读取页面快照
+var ErrNoArchive = errors.New("snapshot archive is not configured")
+
+// snapshotContextKey 是 colly 请求上下文中页面快照键的名称，来源据此记录文章出自哪个列表页快照
+const snapshotContextKey = "snapshot"
+
+// snapshotTimeLayout 是快照文件名中的抓取时间格式
+const snapshotTimeLayout = "20060102T150405.000000000Z"
+
+// snapshotArchive 以 gzip 压缩保存抓取到的原始页面，用于之后离线重新解析
+//
+// 快照按 <URL哈希前两位>/<URL哈希>/<抓取时间>.html.gz 存放，同一URL的多次抓取
+// 互不覆盖。gzip 头中记录原始URL与抓取时间。
+type snapshotArchive struct {
+	dir string
+}
+
+func newSnapshotArchive(dir string) *snapshotArchive {
+	return &snapshotArchive{dir: dir}
+}
+
+// save 保存页面并返回快照键（相对于归档目录的路径）
+func (a *snapshotArchive) save(pageURL string, fetchedAt time.Time, body []byte) (string, error) {
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(pageURL)))
+	key := filepath.ToSlash(filepath.Join(hash[:2], hash, fetchedAt.UTC().Format(snapshotTimeLayout)+".html.gz"))
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Comment = pageURL
+	zw.ModTime = fetchedAt
+	if _, err := zw.Write(body); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(a.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(path, buf.Bytes()); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// load 读取并解压快照，同时返回快照的原始URL
+func (a *snapshotArchive) load(key string) ([]byte, string, error) {
+	f, err := os.Open(filepath.Join(a.dir, filepath.FromSlash(filepath.Clean(key))))
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, "", err
+	}
+	defer zr.Close()
+
+	body, err := io.ReadAll(zr)
+	return body, zr.Comment, err
+}
+
+// prune 删除 before 之前抓取、且不在 keep 中的快照，返回删除的快照数
+func (a *snapshotArchive) prune(before time.Time, keep map[string]bool) (int, error) {
+	removed := 0
+	err := filepath.WalkDir(a.dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".html.gz") {
+			return nil
+		}
+
+		fetchedAt, err := time.Parse(snapshotTimeLayout, strings.TrimSuffix(d.Name(), ".html.gz"))
+		if err != nil || !fetchedAt.Before(before) {
+			return nil
+		}
+		rel, err := filepath.Rel(a.dir, path)
+		if err != nil || keep[filepath.ToSlash(rel)] {
+			return nil
+		}
+
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+		// 同一URL的快照都删除后，去掉空目录
+		os.Remove(filepath.Dir(path))
+		return nil
+	})
+	return removed, err
+}
+
+// archivePages 在 collector 上注册回调，将每个成功抓取的页面存入归档，
+// 每保存一个快照调用一次 saved
+//
+// 快照键同时写入请求上下文，列表页解析出的文章用它记录 ListingSnapshot。
+func (s *Scraper) archivePages(c *colly.Collector, saved func(pageURL, key string)) {
+	if s.archive == nil {
+		return
+	}
+
+	c.OnResponse(func(r *colly.Response) {
+		// 跳过解析的页面与上次保存的快照相同
+		if skipped(r) {
+			return
+		}
+		pageURL := r.Request.URL.String()
+		key, err := s.archive.save(pageURL, time.Now(), r.Body)
+		if err != nil {
+			log.Printf("Failed to archive %s: %v", pageURL, err)
+			return
+		}
+		r.Ctx.Put(snapshotContextKey, key)
+		if saved != nil {
+			saved(pageURL, key)
+		}
+	})
+}
+
+// requestSnapshot 返回请求所得页面的快照键，未归档时为空
+func requestSnapshot(r *colly.Request) string {
+	if r.Ctx == nil {
+		return ""
+	}
+	return r.Ctx.Get(snapshotContextKey)
+}
+
+// PruneArchive 删除 before 之前抓取、且不在 keep 中的页面快照，返回删除的快照数
+//
+// keep 是仍被文章引用的快照键，通常在清理旧文章之后调用。未配置 archive_dir 时不做任何事。
+func (s *Scraper) PruneArchive(before time.Time, keep map[string]bool) (int, error) {
+	if s.archive == nil {
+		return 0, nil
+	}
+	return s.archive.prune(before, keep)
+}
+
+// ReparseSnapshot 对归档的详情页快照重新运行正文与元信息提取，不访问网络
+//
+// 用于改进选择器或提取算法之后回填已有文章。
+func (s *Scraper) ReparseSnapshot(articleURL, key string) (ArticleDetails, error) {
+	if s.archive == nil {
+		return ArticleDetails{}, ErrNoArchive
+	}
+
+	body, _, err := s.archive.load(key)
+	if err != nil {
+		return ArticleDetails{}, fmt.Errorf("load snapshot %s: %w", key, err)
+	}
+
+	c := s.newCollector(context.Background(), "")
+	c.WithTransport(snapshotTransport(body))
+
+	details, err := s.scrapeArticleDetails(c, articleURL)
+	details.Snapshot = key
+	return details, err
+}
+
+// reparseSince 是重新解析增量来源的列表页快照时使用的起始时间，快照中的条目都要解析
+var reparseSince = time.Unix(0, 0)
+
+// ReparseListingSnapshot 对归档的列表页快照重新运行 source 的列表解析，不访问网络
+//
+// 返回的文章以列表链接生成的 ID 标识，ListingSnapshot 为 key。
+func (s *Scraper) ReparseListingSnapshot(source, key string) ([]Article, error) {
+	if s.archive == nil {
+		return nil, ErrNoArchive
+	}
+	src, ok := s.sources.Lookup(source)
+	if !ok {
+		return nil, fmt.Errorf("unknown source %q", source)
+	}
+
+	body, pageURL, err := s.archive.load(key)
+	if err != nil {
+		return nil, fmt.Errorf("load snapshot %s: %w", key, err)
+	}
+	if pageURL == "" {
+		return nil, fmt.Errorf("snapshot %s has no url", key)
+	}
+
+	c := s.newCollector(context.Background(), source)
+	c.WithTransport(snapshotTransport(body))
+	run := newSourceRun("reparse", src)
+	run.track(c)
+
+	if incremental, ok := src.(IncrementalSource); ok {
+		incremental.ParseListingSince(c, run, reparseSince)
+	} else {
+		src.ParseListing(c, run)
+	}
+	if err := c.Visit(pageURL); err != nil {
+		return nil, fmt.Errorf("reparse %s: %w", pageURL, err)
+	}
+
+	articles, _ := run.finish()
+	for i := range articles {
+		articles[i].ListingSnapshot = key
+	}
+	return articles, nil
+}
+
+// snapshotTransport 对任何请求都返回同一份快照内容
+type snapshotTransport []byte
+
+func (t snapshotTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fixtureResponse(req, http.StatusOK, "text/html; charset=utf-8", t), nil
+}
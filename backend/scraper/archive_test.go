@@ -0,0 +1,145 @@
+package scraper
+
+import (
+	"compress/gzip"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSnapshotArchive(t *testing.T) {
+	archive := newSnapshotArchive(t.TempDir())
+	fetchedAt := time.Date(2024, time.March, 5, 10, 30, 0, 0, time.UTC)
+	pageURL := "https://www.example.com/news/zelda"
+
+	key, err := archive.save(pageURL, fetchedAt, []byte(articlePage))
+	if err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+
+	// 快照是带原始URL与抓取时间的 gzip 文件
+	f, err := os.Open(filepath.Join(archive.dir, filepath.FromSlash(key)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("snapshot is not gzip: %v", err)
+	}
+	if zr.Comment != pageURL || !zr.ModTime.Equal(fetchedAt) {
+		t.Errorf("gzip header = %q %v, want %q %v", zr.Comment, zr.ModTime, pageURL, fetchedAt)
+	}
+
+	body, loadedURL, err := archive.load(key)
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if string(body) != articlePage || loadedURL != pageURL {
+		t.Errorf("load() = %d bytes of %q, want the saved page", len(body), loadedURL)
+	}
+
+	// 同一URL的多次抓取互不覆盖
+	later, err := archive.save(pageURL, fetchedAt.Add(time.Hour), []byte("updated"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if later == key {
+		t.Fatal("a later fetch overwrote the snapshot")
+	}
+
+	// 只删除 before 之前且不在 keep 中的快照
+	removed, err := archive.prune(fetchedAt.Add(2*time.Hour), map[string]bool{later: true})
+	if err != nil || removed != 1 {
+		t.Fatalf("prune() = %d, %v, want 1", removed, err)
+	}
+	if _, _, err := archive.load(key); !os.IsNotExist(err) {
+		t.Errorf("pruned snapshot load() error = %v", err)
+	}
+	if _, _, err := archive.load(later); err != nil {
+		t.Errorf("kept snapshot load() error = %v", err)
+	}
+}
+
+const archivedListingPage = `<html><body>
+	<div class="item"><a href="/news/zelda">Zelda sequel announced</a><p>Nintendo shows the first trailer</p><time>2024-03-05</time></div>
+	<div class="item"><a href="/news/cs2">Counter-Strike 2 update</a><p>Valve reworks the economy</p><time>2024-03-04</time></div>
+</body></html>`
+
+func TestReparseSnapshots(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/news", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(archivedListingPage))
+	})
+	mux.HandleFunc("/news/zelda", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(articlePage))
+	})
+	server := httptest.NewServer(mux)
+
+	s := NewScraperWithConfig(&Config{
+		ArchiveDir: t.TempDir(),
+		Politeness: PolitenessConfig{Default: DomainPolicy{Parallelism: 2}},
+		Sources: []SourceConfig{{
+			Name:       "Example",
+			ListingURL: StringList{server.URL + "/news"},
+			Selectors: SelectorConfig{
+				Item:       "div.item",
+				Title:      StringList{"a"},
+				Link:       StringList{"a"},
+				Summary:    StringList{"p"},
+				Date:       StringList{"time"},
+				DateLayout: "2006-01-02",
+			},
+		}},
+	})
+
+	scraped, reports, err := s.ScrapeGamesWithReports()
+	if err != nil || len(scraped) != 2 {
+		t.Fatalf("ScrapeGamesWithReports() = %d articles, %v", len(scraped), err)
+	}
+	if len(reports[0].Snapshots) != 1 || scraped[0].ListingSnapshot != reports[0].Snapshots[0] {
+		t.Fatalf("listing snapshots = %q, article snapshot %q", reports[0].Snapshots, scraped[0].ListingSnapshot)
+	}
+	details, err := s.ScrapeArticleDetails(scraped[0].URL)
+	if err != nil || details.Snapshot == "" {
+		t.Fatalf("ScrapeArticleDetails() snapshot %q, error %v", details.Snapshot, err)
+	}
+
+	// 重新解析只读取归档，不访问站点
+	server.Close()
+
+	reparsed, err := s.ReparseListingSnapshot("Example", scraped[0].ListingSnapshot)
+	if err != nil {
+		t.Fatalf("ReparseListingSnapshot() error = %v", err)
+	}
+	if len(reparsed) != len(scraped) {
+		t.Fatalf("reparsed %d articles, want %d", len(reparsed), len(scraped))
+	}
+	for i, want := range scraped {
+		got := reparsed[i]
+		if got.ID != want.ID || got.Title != want.Title || got.URL != want.URL || got.Summary != want.Summary ||
+			!got.PublishedAt.Equal(want.PublishedAt) || got.Source != want.Source || got.ListingSnapshot != want.ListingSnapshot {
+			t.Errorf("reparsed article %d = %+v, want %+v", i, got, want)
+		}
+	}
+
+	reparsedDetails, err := s.ReparseSnapshot(scraped[0].URL, details.Snapshot)
+	if err != nil {
+		t.Fatalf("ReparseSnapshot() error = %v", err)
+	}
+	if reparsedDetails.Content == "" || reparsedDetails.Content != details.Content ||
+		reparsedDetails.Metadata != details.Metadata || reparsedDetails.Snapshot != details.Snapshot {
+		t.Errorf("ReparseSnapshot() = %+v, want %+v", reparsedDetails, details)
+	}
+
+	if _, err := s.ReparseListingSnapshot("Missing", scraped[0].ListingSnapshot); err == nil {
+		t.Error("ReparseListingSnapshot() of an unknown source succeeded")
+	}
+	if _, err := NewScraperWithConfig(&Config{}).ReparseSnapshot(scraped[0].URL, details.Snapshot); !errors.Is(err, ErrNoArchive) {
+		t.Errorf("ReparseSnapshot() without an archive error = %v, want ErrNoArchive", err)
+	}
+}
@@ -0,0 +1,124 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// ErrNotPaginated 表示来源没有配置翻页，不能回溯抓取
+var ErrNotPaginated = errors.New("source has no pagination")
+
+// PaginatedSource 是列表页可以翻页的来源，回溯抓取时用它找到更早的列表页
+type PaginatedSource interface {
+	Source
+
+	// ParsePagination 在列表页收集器上注册回调，找到下一页地址时调用 next，
+	// page 是当前页的页码（从1开始）
+	ParsePagination(c *colly.Collector, page int, next func(pageURL string))
+}
+
+// paginatedSource 是配置了 pagination 的选择器来源
+type paginatedSource struct {
+	*selectorSource
+}
+
+func (s paginatedSource) ParsePagination(c *colly.Collector, page int, next func(pageURL string)) {
+	pagination := s.cfg.Pagination
+
+	if pagination.PageURL != "" {
+		c.OnResponse(func(r *colly.Response) {
+			next(strings.ReplaceAll(pagination.PageURL, "{page}", strconv.Itoa(page+1)))
+		})
+		return
+	}
+
+	c.OnHTML(strings.Join(pagination.Next, ", "), func(e *colly.HTMLElement) {
+		if href := strings.TrimSpace(e.Attr("href")); href != "" {
+			next(s.absolute(e, href))
+		}
+	})
+}
+
+// ListingPage 是回溯抓取时一个列表页的结果
+type ListingPage struct {
+	URL      string
+	Page     int
+	Articles []Article
+
+	// Next 下一页的地址，没有更多页时为空
+	Next string
+
+	Report SourceReport
+}
+
+// ScrapeListingPage 抓取来源的一个列表页，返回其中的文章和下一页地址
+//
+// pageURL 为空时从来源的第一个列表页开始，page 是 pageURL 的页码。详情页不在这里抓取，
+// 由调用方像定时抓取一样入库。ctx 取消时中止请求并返回 ctx 的错误。
+func (s *Scraper) ScrapeListingPage(ctx context.Context, source, pageURL string, page int) (ListingPage, error) {
+	src, ok := s.sources.Lookup(source)
+	if !ok {
+		return ListingPage{}, fmt.Errorf("unknown source %q", source)
+	}
+	paginated, ok := src.(PaginatedSource)
+	if !ok {
+		return ListingPage{}, fmt.Errorf("%s: %w", source, ErrNotPaginated)
+	}
+
+	if pageURL == "" {
+		listings := src.Discover()
+		if len(listings) == 0 {
+			return ListingPage{}, fmt.Errorf("%s: no listing url", source)
+		}
+		pageURL = listings[0]
+		page = 1
+	}
+
+	c := s.newCollector(ctx, src.Name())
+	run := newSourceRun(fmt.Sprintf("backfill-%d", time.Now().UnixNano()), src)
+	run.track(c)
+	s.archivePages(c, run.snapshot)
+
+	src.ParseListing(c, run)
+
+	var next string
+	paginated.ParsePagination(c, page, func(nextURL string) {
+		// 只取第一个下一页链接，指回当前页的链接不算
+		if next == "" && nextURL != pageURL {
+			next = nextURL
+		}
+	})
+
+	if err := c.Visit(pageURL); err != nil && !run.visited(pageURL) {
+		run.Error(fmt.Errorf("%s: %w", pageURL, err))
+	}
+	c.Wait()
+
+	articles, report := run.finish()
+	if err := ctx.Err(); err != nil {
+		return ListingPage{URL: pageURL, Page: page}, err
+	}
+	result := ListingPage{
+		URL:      pageURL,
+		Page:     page,
+		Articles: articles,
+		Next:     next,
+		Report:   report,
+	}
+
+	// 按页码模板翻过最后一页时站点通常返回404，视为没有更多页
+	if report.ItemsParsed == 0 && report.Statuses[http.StatusNotFound] > 0 {
+		return result, nil
+	}
+	if report.Failed() {
+		return result, fmt.Errorf("%s: %s", pageURL, strings.Join(report.Errors, "; "))
+	}
+	return result, nil
+}
@@ -0,0 +1,192 @@
+package scraper
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// 并发抓取的默认值
+const (
+	// DefaultSourceWorkers 是同时抓取的来源数
+	DefaultSourceWorkers = 4
+
+	// DefaultMaxRequests 是所有来源同时进行的请求总数上限
+	DefaultMaxRequests = 8
+)
+
+// requestBudget 限制所有来源同时进行的请求总数
+//
+// 名额用完时请求按来源排队，释放的名额优先分给当前占用名额最少的来源，
+// 占用相同时先到先得。这样请求多的来源不会挤占其他来源，慢的来源也只拖慢自己。
+type requestBudget struct {
+	mu      sync.Mutex
+	free    int
+	held    map[string]int
+	waiters []*budgetWaiter
+}
+
+// budgetWaiter 是一个等待名额的请求，分到名额时关闭 ready
+type budgetWaiter struct {
+	source string
+	ready  chan struct{}
+}
+
+func newRequestBudget(size int) *requestBudget {
+	return &requestBudget{free: size, held: make(map[string]int)}
+}
+
+// acquire 为 source 占用一个名额，ctx 取消时放弃排队
+func (b *requestBudget) acquire(ctx context.Context, source string) error {
+	b.mu.Lock()
+	if b.free > 0 {
+		b.free--
+		b.held[source]++
+		b.mu.Unlock()
+		return nil
+	}
+	w := &budgetWaiter{source: source, ready: make(chan struct{})}
+	b.waiters = append(b.waiters, w)
+	b.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	select {
+	case <-w.ready:
+		// 取消的同时分到了名额，转交给其他来源
+		b.releaseLocked(source)
+	default:
+		for i, waiter := range b.waiters {
+			if waiter == w {
+				b.waiters = append(b.waiters[:i], b.waiters[i+1:]...)
+				break
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// release 归还 source 占用的一个名额
+func (b *requestBudget) release(source string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.releaseLocked(source)
+}
+
+func (b *requestBudget) releaseLocked(source string) {
+	b.held[source]--
+	if b.held[source] <= 0 {
+		delete(b.held, source)
+	}
+	b.free++
+
+	for b.free > 0 && len(b.waiters) > 0 {
+		next := 0
+		for i, w := range b.waiters {
+			if b.held[w.source] < b.held[b.waiters[next].source] {
+				next = i
+			}
+		}
+
+		w := b.waiters[next]
+		b.waiters = append(b.waiters[:next], b.waiters[next+1:]...)
+		b.free--
+		b.held[w.source]++
+		close(w.ready)
+	}
+}
+
+// budgetTransport 让来源的每个请求占用一个全局名额，直到响应正文关闭
+//
+// 名额不在这里占用：它把 budgetTicket 放进请求的 context，由按域名限速的 politeTransport
+// 在限速器放行之后调用 admitBudget 占用。这样等待域名并发数、请求间隔和 Crawl-delay
+// 的请求不占名额，不会让其他域名的请求空等。fixture 模式不经过限速，也不占用名额。
+type budgetTransport struct {
+	base   http.RoundTripper
+	budget *requestBudget
+	source string
+}
+
+func (t *budgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ticket := &budgetTicket{budget: t.budget, source: t.source}
+	req = req.WithContext(context.WithValue(req.Context(), budgetTicketKey{}, ticket))
+
+	resp, err := t.base.RoundTrip(req)
+	if !ticket.held {
+		return resp, err
+	}
+	if err != nil {
+		t.budget.release(t.source)
+		return nil, err
+	}
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: func() {
+		t.budget.release(t.source)
+	}}
+	return resp, nil
+}
+
+// budgetTicketKey 是请求 context 中 budgetTicket 的键
+type budgetTicketKey struct{}
+
+// budgetTicket 是一个请求待占用的全局名额
+type budgetTicket struct {
+	budget *requestBudget
+	source string
+	held   bool
+}
+
+// admitBudget 为请求占用全局名额，请求没有经过 budgetTransport 或已经占用时什么也不做
+func admitBudget(req *http.Request) error {
+	ticket, _ := req.Context().Value(budgetTicketKey{}).(*budgetTicket)
+	if ticket == nil || ticket.held {
+		return nil
+	}
+	if err := ticket.budget.acquire(req.Context(), ticket.source); err != nil {
+		return err
+	}
+	ticket.held = true
+	return nil
+}
+
+// releaseOnClose 在正文第一次关闭时归还名额
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releaseOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// interleaveBySource 返回 articles 的下标，各来源的文章轮流排列，来源内保持原顺序
+func interleaveBySource(articles []Article) []int {
+	order := make([]string, 0)
+	bySource := make(map[string][]int)
+	for i, article := range articles {
+		if _, ok := bySource[article.Source]; !ok {
+			order = append(order, article.Source)
+		}
+		bySource[article.Source] = append(bySource[article.Source], i)
+	}
+
+	indexes := make([]int, 0, len(articles))
+	for round := 0; len(indexes) < len(articles); round++ {
+		for _, source := range order {
+			if round < len(bySource[source]) {
+				indexes = append(indexes, bySource[source][round])
+			}
+		}
+	}
+	return indexes
+}
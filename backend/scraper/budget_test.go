@@ -0,0 +1,179 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// acquired 判断 acquire 是否已经返回
+func acquired(done <-chan error) bool {
+	select {
+	case <-done:
+		return true
+	case <-time.After(20 * time.Millisecond):
+		return false
+	}
+}
+
+// queue 在后台为 source 排队等待名额，返回 acquire 的结果
+func queue(ctx context.Context, b *requestBudget, source string) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- b.acquire(ctx, source)
+	}()
+	// 等请求进入队列，保证排队顺序
+	for !waiting(b, source) {
+		time.Sleep(time.Millisecond)
+	}
+	return done
+}
+
+// waiting 判断 source 是否有请求在排队
+func waiting(b *requestBudget, source string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, w := range b.waiters {
+		if w.source == source {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRequestBudgetFairness(t *testing.T) {
+	b := newRequestBudget(2)
+	ctx := context.Background()
+
+	// 请求多的来源先占满名额
+	for i := 0; i < 2; i++ {
+		if err := b.acquire(ctx, "Busy"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	busy := queue(ctx, b, "Busy")
+	quiet := queue(ctx, b, "Quiet")
+	if acquired(busy) || acquired(quiet) {
+		t.Fatal("acquire() returned while the budget was full")
+	}
+
+	// 释放的名额给占用最少的来源，即使它排在后面
+	b.release("Busy")
+	if !acquired(quiet) {
+		t.Fatal("released slot did not go to the source holding fewer slots")
+	}
+	if acquired(busy) {
+		t.Fatal("released slot went to the source already holding one")
+	}
+
+	// 占用相同时先到先得
+	other := queue(ctx, b, "Other")
+	b.release("Quiet")
+	if !acquired(other) || acquired(busy) {
+		t.Error("released slot did not go to the source holding none")
+	}
+	b.release("Busy")
+	if !acquired(busy) {
+		t.Error("queued request never got a slot")
+	}
+
+	if b.free != 0 || b.held["Busy"] != 1 || b.held["Other"] != 1 {
+		t.Errorf("budget = %d free, held %v", b.free, b.held)
+	}
+}
+
+func TestRequestBudgetCancel(t *testing.T) {
+	b := newRequestBudget(1)
+	if err := b.acquire(context.Background(), "Busy"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := queue(ctx, b, "Cancelled")
+	next := queue(context.Background(), b, "Waiting")
+	cancel()
+	if err := <-cancelled; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled acquire() error = %v", err)
+	}
+
+	// 取消的请求离开队列，名额交给后面的请求
+	b.release("Busy")
+	if !acquired(next) {
+		t.Fatal("slot went to a cancelled request")
+	}
+	b.release("Waiting")
+	if b.free != 1 || len(b.held) != 0 || len(b.waiters) != 0 {
+		t.Errorf("budget = %d free, held %v, %d waiting", b.free, b.held, len(b.waiters))
+	}
+}
+
+func TestBudgetTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("page"))
+	}))
+	defer server.Close()
+
+	b := newRequestBudget(1)
+	// admitBudget 由 politeTransport 调用，这里直接在下层占用名额
+	admit := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if err := admitBudget(req); err != nil {
+			return nil, err
+		}
+		return http.DefaultTransport.RoundTrip(req)
+	})
+	transport := &budgetTransport{base: admit, budget: b, source: "Example"}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.free != 0 {
+		t.Fatal("request did not hold a slot")
+	}
+
+	// 名额在正文关闭时归还，重复关闭只归还一次
+	resp.Body.Close()
+	resp.Body.Close()
+	if b.free != 1 || len(b.held) != 0 {
+		t.Errorf("budget after close = %d free, held %v", b.free, b.held)
+	}
+
+	// 出错的请求立即归还名额
+	req, _ = http.NewRequest(http.MethodGet, "http://127.0.0.1:1/", nil)
+	if _, err := transport.RoundTrip(req); err == nil {
+		t.Fatal("request to a closed port succeeded")
+	}
+	if b.free != 1 {
+		t.Errorf("budget after an error = %d free", b.free)
+	}
+}
+
+// roundTripFunc 把函数用作 http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestInterleaveBySource(t *testing.T) {
+	articles := []Article{
+		{ID: "a1", Source: "A"}, {ID: "a2", Source: "A"}, {ID: "a3", Source: "A"},
+		{ID: "b1", Source: "B"},
+		{ID: "c1", Source: "C"}, {ID: "c2", Source: "C"},
+	}
+
+	got := interleaveBySource(articles)
+	want := []int{0, 3, 4, 1, 5, 2}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("interleaveBySource() = %v, want %v", got, want)
+	}
+
+	if got := interleaveBySource(nil); len(got) != 0 {
+		t.Errorf("interleaveBySource(nil) = %v", got)
+	}
+}
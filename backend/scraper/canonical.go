@@ -0,0 +1,99 @@
+package scraper
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// trackingParams 是与文章内容无关、只用于统计来源的查询参数
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"msclkid": true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"ocid":    true,
+	"_ga":     true,
+	"igshid":  true,
+}
+
+// CanonicalizeURL 规范化文章URL，使同一篇文章的不同链接得到相同的结果
+//
+// 协议和域名转为小写，去掉默认端口、片段（#...）以及 utm_* 等跟踪参数，
+// 其余查询参数按名称排序。无法解析的URL原样返回。
+func CanonicalizeURL(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	if port := u.Port(); port != "" && !(u.Scheme == "http" && port == "80") && !(u.Scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	u.Host = host
+
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	query := u.Query()
+	for name := range query {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "utm_") || trackingParams[lower] {
+			query.Del(name)
+		}
+	}
+	// Encode 会按参数名排序
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// ArticleID 根据文章链接生成ID
+//
+// 链接先经过 CanonicalizeURL，并忽略协议与 www. 前缀，因此带跟踪参数、
+// http/https 混用的链接得到同一个ID。ID 取 SHA-256 的前 128 位（32个十六进制字符）。
+func ArticleID(link string) string {
+	return articleID(identityKey(link))
+}
+
+// articleID 根据键生成文章ID
+func articleID(key string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(key)))[:32]
+}
+
+// identityKey 返回用于生成ID的URL形式：规范化后去掉协议与 www. 前缀
+func identityKey(link string) string {
+	canonical := CanonicalizeURL(link)
+	u, err := url.Parse(canonical)
+	if err != nil || u.Host == "" {
+		return canonical
+	}
+
+	u.Scheme = ""
+	u.Host = strings.TrimPrefix(u.Host, "www.")
+	return u.String()
+}
+
+// CanonicalArticleID 返回文章的规范ID
+//
+// 详情页声明了 rel=canonical 时以其为准，这样同一篇文章从不同列表链接进入也只保存一份。
+// 指向其它站点或站点首页的 canonical 通常是配置错误，此时仍使用列表链接生成的ID。
+func CanonicalArticleID(article Article) string {
+	if article.CanonicalURL == "" || hostOf(article.CanonicalURL) != hostOf(article.URL) {
+		return article.ID
+	}
+
+	u, err := url.Parse(article.CanonicalURL)
+	if err != nil || strings.Trim(u.Path, "/") == "" {
+		return article.ID
+	}
+	return ArticleID(article.CanonicalURL)
+}
@@ -0,0 +1,91 @@
+package scraper
+
+import "testing"
+
+func TestCanonicalizeURL(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{"already canonical", "https://www.ign.com/articles/zelda", "https://www.ign.com/articles/zelda"},
+		{"scheme and host lowercased", "HTTPS://WWW.IGN.COM/articles/Zelda", "https://www.ign.com/articles/Zelda"},
+		{"default https port", "https://www.ign.com:443/articles/zelda", "https://www.ign.com/articles/zelda"},
+		{"default http port", "http://www.ign.com:80/articles/zelda", "http://www.ign.com/articles/zelda"},
+		{"other port kept", "https://www.ign.com:8443/articles/zelda", "https://www.ign.com:8443/articles/zelda"},
+		{"fragment removed", "https://www.ign.com/articles/zelda#comments", "https://www.ign.com/articles/zelda"},
+		{"empty path", "https://www.ign.com", "https://www.ign.com/"},
+		{"utm parameters removed", "https://www.ign.com/articles/zelda?utm_source=twitter&UTM_Medium=social", "https://www.ign.com/articles/zelda"},
+		{"click ids removed", "https://www.ign.com/articles/zelda?fbclid=abc&gclid=def&ocid=ghi", "https://www.ign.com/articles/zelda"},
+		{"other parameters sorted", "https://www.ign.com/watch?v=2&id=1&utm_campaign=x", "https://www.ign.com/watch?id=1&v=2"},
+		{"surrounding space", "  https://www.ign.com/articles/zelda\n", "https://www.ign.com/articles/zelda"},
+		{"relative url unchanged", "/articles/zelda", "/articles/zelda"},
+		{"invalid url unchanged", "https://www.ign.com/%zz", "https://www.ign.com/%zz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CanonicalizeURL(tt.url); got != tt.want {
+				t.Errorf("CanonicalizeURL(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArticleID(t *testing.T) {
+	const base = "https://www.gamespot.com/articles/zelda-sequel/1100-6500000/"
+
+	tests := []struct {
+		name string
+		url  string
+		same bool
+	}{
+		{"identical", base, true},
+		{"http", "http://www.gamespot.com/articles/zelda-sequel/1100-6500000/", true},
+		{"without www", "https://gamespot.com/articles/zelda-sequel/1100-6500000/", true},
+		{"other parameters kept", base + "?utm_source=rss&ftag=x#comments", false},
+		{"only tracking parameters", base + "?utm_source=rss#comments", true},
+		{"upper case host", "https://WWW.GameSpot.com/articles/zelda-sequel/1100-6500000/", true},
+		{"different path", "https://www.gamespot.com/articles/zelda-review/1100-6500001/", false},
+		{"different query", base + "?page=2", false},
+		{"trailing slash", "https://www.gamespot.com/articles/zelda-sequel/1100-6500000", false},
+	}
+
+	want := ArticleID(base)
+	if len(want) != 32 {
+		t.Fatalf("ArticleID() = %q, want 32 hex characters", want)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ArticleID(tt.url); (got == want) != tt.same {
+				t.Errorf("ArticleID(%q) == ArticleID(%q) is %v, want %v", tt.url, base, got == want, tt.same)
+			}
+		})
+	}
+}
+
+func TestCanonicalArticleID(t *testing.T) {
+	const listed = "https://www.ign.com/articles/zelda?src=home"
+	listedID := ArticleID(listed)
+
+	tests := []struct {
+		name      string
+		canonical string
+		want      string
+	}{
+		{"no canonical", "", listedID},
+		{"same site", "https://www.ign.com/articles/zelda", ArticleID("https://www.ign.com/articles/zelda")},
+		{"same site without www", "https://ign.com/articles/zelda", ArticleID("https://www.ign.com/articles/zelda")},
+		{"other site", "https://www.gamespot.com/articles/zelda", listedID},
+		{"home page", "https://www.ign.com/", listedID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			article := Article{ID: listedID, URL: listed, CanonicalURL: tt.canonical}
+			if got := CanonicalArticleID(article); got != tt.want {
+				t.Errorf("CanonicalArticleID(%q) = %q, want %q", tt.canonical, got, tt.want)
+			}
+		})
+	}
+}
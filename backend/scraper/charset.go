@@ -0,0 +1,162 @@
+package scraper
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/saintfish/chardet"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// maxTextBytes 限制转换编码时读取的正文大小，colly 默认也只读取前 10MB
+const maxTextBytes = 50 << 20
+
+// xmlEncodingPattern 匹配 XML 声明中的编码
+var xmlEncodingPattern = regexp.MustCompile(`^(\s*<\?xml[^>]*?\bencoding\s*=\s*["'])([^"']+)(["'])`)
+
+// metaCharsetPattern 匹配 <meta charset> 以及 http-equiv Content-Type 中的编码
+var metaCharsetPattern = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([a-z0-9_.:-]+)`)
+
+// metaScanBytes 是查找 <meta> 编码声明的范围，不少中文站点把它放在较长的头部之后
+const metaScanBytes = 4096
+
+// charsetTransport 把文本响应统一转换为 UTF-8
+//
+// 国内不少站点仍使用 GBK/GB2312 或 Big5，编码可能只写在 <meta> 或 XML 声明里，
+// 也可能与响应头不一致。转换后响应头标为 charset=utf-8，XML 声明中的编码也改为
+// UTF-8，之后的解析、归档和重新解析都只需处理 UTF-8。
+type charsetTransport struct {
+	base http.RoundTripper
+
+	// charset 来源配置中指定的编码，为空时自动判断
+	charset string
+}
+
+func (t charsetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || !textualResponse(resp) {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTextBytes))
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// 未声明 Content-Encoding 的 .xml.gz 由 colly 解压，这里原样返回
+	if !bytes.HasPrefix(body, []byte{0x1f, 0x8b}) {
+		contentType := resp.Header.Get("Content-Type")
+		body = toUTF8(body, contentType, t.charset)
+		body = xmlEncodingPattern.ReplaceAll(body, []byte("${1}UTF-8${3}"))
+
+		if mediaType, params, err := mime.ParseMediaType(contentType); err == nil {
+			params["charset"] = "utf-8"
+			resp.Header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+		}
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return resp, nil
+}
+
+// textualResponse 判断响应是否是需要转换编码的文本（HTML、XML、JSON 等）
+func textualResponse(resp *http.Response) bool {
+	if resp.Body == nil || resp.Body == http.NoBody {
+		return false
+	}
+	if encoding := resp.Header.Get("Content-Encoding"); encoding != "" && encoding != "identity" && !resp.Uncompressed {
+		return false
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType == "" || strings.HasPrefix(mediaType, "text/") ||
+		strings.Contains(mediaType, "xml") || strings.Contains(mediaType, "html") || strings.Contains(mediaType, "json")
+}
+
+// toUTF8 将正文转换为 UTF-8
+//
+// 指定了 forced 时直接按其解码；否则合法的 UTF-8 原样返回，即使声明的是其他编码，
+// 因为 GBK、Big5 等编码的正文几乎不可能恰好是合法的 UTF-8。其余情况依次使用
+// BOM、响应头、XML 声明与 <meta> 中的编码，都没有时按内容猜测。
+func toUTF8(body []byte, contentType, forced string) []byte {
+	label := forced
+	if label == "" {
+		if utf8.Valid(body) {
+			return body
+		}
+		label = declaredCharset(body, contentType)
+		if label == "" {
+			label = detectCharset(body, contentType)
+		}
+	}
+
+	enc, name := charset.Lookup(label)
+	if enc == nil || name == "utf-8" {
+		return body
+	}
+	// GB18030 兼容 GBK 与 GB2312，声明为 GB2312 的页面也常含有超出其范围的字
+	if name == "gbk" {
+		enc = simplifiedchinese.GB18030
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return body
+	}
+	return decoded
+}
+
+// declaredCharset 返回正文声明的编码，没有声明或声明为 UTF-8 时返回空字符串
+//
+// 调用前已确认正文不是合法的 UTF-8，声明为 UTF-8 的视为声明错误。
+func declaredCharset(body []byte, contentType string) string {
+	if _, name, certain := charset.DetermineEncoding(body, contentType); certain && name != "utf-8" {
+		return name
+	}
+
+	head := body
+	if len(head) > metaScanBytes {
+		head = head[:metaScanBytes]
+	}
+	labels := make([]string, 0, 2)
+	if match := xmlEncodingPattern.FindSubmatch(head); match != nil {
+		labels = append(labels, string(match[2]))
+	}
+	if match := metaCharsetPattern.FindSubmatch(head); match != nil {
+		labels = append(labels, string(match[1]))
+	}
+	for _, label := range labels {
+		if _, name := charset.Lookup(label); name != "" && name != "utf-8" {
+			return name
+		}
+	}
+	return ""
+}
+
+// detectCharset 按内容猜测编码，无法判断时返回空字符串
+func detectCharset(body []byte, contentType string) string {
+	detector := chardet.NewTextDetector()
+	if strings.Contains(contentType, "html") {
+		detector = chardet.NewHtmlDetector()
+	}
+
+	result, err := detector.DetectBest(body)
+	if err != nil {
+		return ""
+	}
+	// chardet 的名称不全是标准标签
+	if result.Charset == "GB-18030" {
+		return "gb18030"
+	}
+	return result.Charset
+}
@@ -0,0 +1,124 @@
+package scraper
+
+import (
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+func encode(t *testing.T, enc encoding.Encoding, s string) []byte {
+	t.Helper()
+	b, err := enc.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatalf("encode %q: %v", s, err)
+	}
+	return b
+}
+
+func TestToUTF8(t *testing.T) {
+	const simplified = "游戏新闻：新作公布，发售日期确定在明年春季。玩家可以在官方网站预约，首发版本附赠限定道具。"
+	const traditional = "遊戲新聞：新作公佈，發售日期確定在明年春季。玩家可以在官方網站預約，首發版本附贈限定道具。"
+
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+		forced      string
+		want        string
+	}{
+		{
+			name:        "gbk from header",
+			body:        encode(t, simplifiedchinese.GBK, "<p>"+simplified+"</p>"),
+			contentType: "text/html; charset=gbk",
+			want:        "<p>" + simplified + "</p>",
+		},
+		{
+			name:        "gb2312 from meta",
+			body:        encode(t, simplifiedchinese.GBK, `<html><head><meta charset="gb2312"></head><body>`+simplified+"</body></html>"),
+			contentType: "text/html",
+			want:        `<html><head><meta charset="gb2312"></head><body>` + simplified + "</body></html>",
+		},
+		{
+			name:        "gbk from http-equiv meta",
+			body:        encode(t, simplifiedchinese.GBK, `<meta http-equiv="Content-Type" content="text/html; charset=GBK"><p>`+simplified+"</p>"),
+			contentType: "text/html",
+			want:        `<meta http-equiv="Content-Type" content="text/html; charset=GBK"><p>` + simplified + "</p>",
+		},
+		{
+			name:        "big5 from xml declaration",
+			body:        encode(t, traditionalchinese.Big5, `<?xml version="1.0" encoding="big5"?><title>`+traditional+"</title>"),
+			contentType: "application/rss+xml",
+			want:        `<?xml version="1.0" encoding="big5"?><title>` + traditional + "</title>",
+		},
+		{
+			name:        "gbk with meta wrongly claiming utf-8",
+			body:        encode(t, simplifiedchinese.GBK, `<html><head><meta charset="utf-8"></head><body><p>`+simplified+simplified+"</p></body></html>"),
+			contentType: "text/html",
+			want:        `<html><head><meta charset="utf-8"></head><body><p>` + simplified + simplified + "</p></body></html>",
+		},
+		{
+			name:        "gbk with header wrongly claiming utf-8",
+			body:        encode(t, simplifiedchinese.GBK, `<meta charset="gbk"><p>`+simplified+"</p>"),
+			contentType: "text/html; charset=utf-8",
+			want:        `<meta charset="gbk"><p>` + simplified + "</p>",
+		},
+		{
+			name:        "valid utf-8 wrongly declared as gbk",
+			body:        []byte(`<meta charset="gbk"><p>` + simplified + "</p>"),
+			contentType: "text/html; charset=gbk",
+			want:        `<meta charset="gbk"><p>` + simplified + "</p>",
+		},
+		{
+			name:        "forced big5",
+			body:        encode(t, traditionalchinese.Big5, traditional),
+			contentType: "text/html; charset=gbk",
+			forced:      "big5",
+			want:        traditional,
+		},
+		{
+			name:        "gb18030 characters outside gb2312",
+			body:        encode(t, simplifiedchinese.GB18030, "<p>𠀀镕</p>"),
+			contentType: "text/html; charset=gb2312",
+			want:        "<p>𠀀镕</p>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(toUTF8(tt.body, tt.contentType, tt.forced)); got != tt.want {
+				t.Errorf("toUTF8() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeclaredCharset(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		want        string
+	}{
+		{"header", "<p>x</p>", "text/html; charset=GBK", "gbk"},
+		{"header gb2312", "<p>x</p>", "text/html; charset=gb2312", "gbk"},
+		{"meta charset", `<meta charset="big5"><p>x</p>`, "text/html", "big5"},
+		{"meta unquoted", `<meta charset=gb2312><p>x</p>`, "text/html", "gbk"},
+		{"http-equiv", `<meta http-equiv="Content-Type" content="text/html; charset=big5">`, "text/html", "big5"},
+		{"xml declaration", `<?xml version="1.0" encoding="GB2312"?><rss></rss>`, "application/xml", "gbk"},
+		{"header utf-8 falls back to meta", `<meta charset="gbk">`, "text/html; charset=utf-8", "gbk"},
+		{"meta utf-8", `<meta charset="utf-8"><p>x</p>`, "text/html", ""},
+		{"header utf-8", "<p>x</p>", "text/html; charset=utf-8", ""},
+		{"none", "<p>x</p>", "text/html", ""},
+		{"unknown label", `<meta charset="klingon">`, "text/html", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := declaredCharset([]byte(tt.body), tt.contentType); got != tt.want {
+				t.Errorf("declaredCharset(%q, %q) = %q, want %q", tt.body, tt.contentType, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigPath 是未设置 SCRAPER_CONFIG 时加载的来源配置文件
+const DefaultConfigPath = "config/sources.yaml"
+
+// DefaultDetailWorkers 是并发抓取详情页的默认协程数
+const DefaultDetailWorkers = 4
+
+// 爬虫运行模式
+const (
+	// ModeLive 访问真实站点（默认）
+	ModeLive = "live"
+
+	// ModeFixture 从 FixtureDir 读取本地保存的页面，不访问网络，用于离线开发与演示
+	ModeFixture = "fixture"
+)
+
+// Config 是爬虫配置文件的内容
+type Config struct {
+	// Mode 运行模式：live（默认）或 fixture
+	Mode string `yaml:"mode" json:"mode"`
+
+	// FixtureDir fixture 模式下的页面目录，按 <域名>/<路径> 保存列表页、订阅源和详情页
+	FixtureDir string `yaml:"fixture_dir" json:"fixture_dir"`
+
+	// UserAgent 爬虫请求使用的标识，未设置时使用 DefaultUserAgent
+	UserAgent string `yaml:"user_agent" json:"user_agent"`
+
+	// RespectRobotsTxt 为 false 时不检查 robots.txt，未设置时默认遵守
+	RespectRobotsTxt *bool `yaml:"respect_robots_txt" json:"respect_robots_txt"`
+
+	// Politeness 按域名设置的并发数与请求间隔
+	Politeness PolitenessConfig `yaml:"politeness" json:"politeness"`
+
+	// CacheDir HTTP缓存目录，保存 ETag/Last-Modified 以发送条件请求，为空时不缓存
+	CacheDir string `yaml:"cache_dir" json:"cache_dir"`
+
+	// CacheMaxAge 缓存条目超过这个时长没有被使用时清理，未设置时使用 DefaultCacheMaxAge
+	CacheMaxAge Duration `yaml:"cache_max_age" json:"cache_max_age"`
+
+	// ArchiveDir 保存列表页和详情页原始HTML快照（gzip压缩）的目录，为空时不保存
+	ArchiveDir string `yaml:"archive_dir" json:"archive_dir"`
+
+	// DetailWorkers 并发抓取详情页的协程数，未设置时使用 DefaultDetailWorkers
+	DetailWorkers int `yaml:"detail_workers" json:"detail_workers"`
+
+	// SourceWorkers 同时抓取的来源数，未设置时使用 DefaultSourceWorkers
+	SourceWorkers int `yaml:"source_workers" json:"source_workers"`
+
+	// MaxRequests 所有来源同时进行的请求总数上限，未设置时使用 DefaultMaxRequests
+	MaxRequests int `yaml:"max_requests" json:"max_requests"`
+
+	// Fetch 请求的超时、重试与熔断策略，来源可以单独覆盖
+	Fetch FetchPolicy `yaml:"fetch" json:"fetch"`
+
+	Sources []SourceConfig `yaml:"sources" json:"sources"`
+}
+
+// SourceConfig 描述一个来源站点
+type SourceConfig struct {
+	// Name 来源名称，写入 Article.Source
+	Name string `yaml:"name" json:"name"`
+
+	// Type 来源类型：html（默认，使用CSS选择器解析列表页）、feed（RSS/Atom订阅源）
+	// 或 sitemap（sitemap.xml 与新闻 sitemap）
+	Type string `yaml:"type" json:"type"`
+
+	// Enabled 为 false 时不抓取该来源，未设置时默认启用
+	Enabled *bool `yaml:"enabled" json:"enabled"`
+
+	// ListingURL 列表页地址，html 类型必填
+	ListingURL StringList `yaml:"listing_url" json:"listing_url"`
+
+	// FeedURL 订阅源地址，feed 类型必填
+	FeedURL StringList `yaml:"feed_url" json:"feed_url"`
+
+	// SitemapURL sitemap 或 sitemap 索引地址，sitemap 类型必填
+	SitemapURL StringList `yaml:"sitemap_url" json:"sitemap_url"`
+
+	// URLPattern 正则表达式，sitemap 中只有匹配的地址才作为文章，为空时全部作为文章
+	URLPattern string `yaml:"url_pattern" json:"url_pattern"`
+
+	// MaxAge sitemap 来源没有上次运行记录时回溯的时长，未设置时使用 DefaultSitemapMaxAge
+	MaxAge Duration `yaml:"max_age" json:"max_age"`
+
+	// URLPrefix 用于补全相对链接和相对图片地址
+	URLPrefix string `yaml:"url_prefix" json:"url_prefix"`
+
+	// Selectors 列表页与详情页使用的选择器
+	Selectors SelectorConfig `yaml:"selectors" json:"selectors"`
+
+	// Pagination 列表页的翻页方式，html 类型配置后可以回溯抓取历史文章
+	Pagination PaginationConfig `yaml:"pagination" json:"pagination"`
+
+	// Fetch 覆盖全局的超时、重试与熔断策略，未设置的字段沿用全局配置
+	Fetch FetchPolicy `yaml:"fetch" json:"fetch"`
+
+	// Charset 页面编码（如 gbk、big5），为空时按响应头、XML 声明、<meta> 或内容自动判断，
+	// 站点声明的编码与实际不符时才需要设置
+	Charset string `yaml:"charset" json:"charset"`
+
+	// Timezone 列表页、订阅源和 sitemap 中不带时区的日期所在的时区（如 Asia/Shanghai），默认 UTC
+	Timezone string `yaml:"timezone" json:"timezone"`
+}
+
+// PaginationConfig 描述列表页的翻页方式，Next 与 PageURL 二选一
+type PaginationConfig struct {
+	// Next 下一页链接的选择器，读取其 href 属性
+	Next StringList `yaml:"next" json:"next"`
+
+	// PageURL 页码地址模板，{page} 替换为页码（第2页起），第1页使用 listing_url
+	PageURL string `yaml:"page_url" json:"page_url"`
+}
+
+// configured 判断是否配置了翻页
+func (p PaginationConfig) configured() bool {
+	return len(p.Next) > 0 || p.PageURL != ""
+}
+
+// SelectorConfig 描述列表页条目与详情页正文的选择器
+//
+// 除 Item 外，每个字段都可以配置多个选择器，按顺序取第一个有结果的。
+type SelectorConfig struct {
+	Item       string     `yaml:"item" json:"item"`
+	Title      StringList `yaml:"title" json:"title"`
+	Link       StringList `yaml:"link" json:"link"`
+	LinkAttr   string     `yaml:"link_attr" json:"link_attr"`
+	Summary    StringList `yaml:"summary" json:"summary"`
+	Image      StringList `yaml:"image" json:"image"`
+	ImageAttr  string     `yaml:"image_attr" json:"image_attr"`
+	Date       StringList `yaml:"date" json:"date"`
+	DateAttr   string     `yaml:"date_attr" json:"date_attr"`
+	DateLayout string     `yaml:"date_layout" json:"date_layout"`
+	Detail     StringList `yaml:"detail" json:"detail"`
+}
+
+// StringList 在配置中既可以写成单个字符串，也可以写成字符串列表
+type StringList []string
+
+// UnmarshalYAML 支持单个字符串或字符串列表
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = StringList{value.Value}
+		return nil
+	}
+
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// UnmarshalJSON 支持单个字符串或字符串列表
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = StringList{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// LoadConfig 从YAML或JSON文件加载来源配置，按扩展名判断格式
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &cfg)
+	} else {
+		err = yaml.Unmarshal(data, &cfg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+
+	return &cfg, nil
+}
+
+// Validate 检查来源配置是否完整
+func (cfg *Config) Validate() error {
+	switch cfg.Mode {
+	case "", ModeLive:
+	case ModeFixture:
+		if cfg.FixtureDir == "" {
+			return fmt.Errorf("fixture_dir is required in %s mode", ModeFixture)
+		}
+	default:
+		return fmt.Errorf("unknown mode %q", cfg.Mode)
+	}
+
+	seen := make(map[string]bool)
+	for i, src := range cfg.Sources {
+		if src.Name == "" {
+			return fmt.Errorf("source #%d: name is required", i+1)
+		}
+		if seen[src.Name] {
+			return fmt.Errorf("source %q: duplicate name", src.Name)
+		}
+		seen[src.Name] = true
+
+		switch src.Type {
+		case "", "html":
+			if len(src.ListingURL) == 0 {
+				return fmt.Errorf("source %q: listing_url is required", src.Name)
+			}
+			if src.Selectors.Item == "" || len(src.Selectors.Title) == 0 || len(src.Selectors.Link) == 0 {
+				return fmt.Errorf("source %q: item, title and link selectors are required", src.Name)
+			}
+			if len(src.Pagination.Next) > 0 && src.Pagination.PageURL != "" {
+				return fmt.Errorf("source %q: pagination accepts either next or page_url", src.Name)
+			}
+			if src.Pagination.PageURL != "" && !strings.Contains(src.Pagination.PageURL, "{page}") {
+				return fmt.Errorf("source %q: pagination page_url must contain {page}", src.Name)
+			}
+		case "feed":
+			if len(src.FeedURL) == 0 {
+				return fmt.Errorf("source %q: feed_url is required", src.Name)
+			}
+		case "sitemap":
+			if len(src.SitemapURL) == 0 {
+				return fmt.Errorf("source %q: sitemap_url is required", src.Name)
+			}
+			if _, err := regexp.Compile(src.URLPattern); err != nil {
+				return fmt.Errorf("source %q: invalid url_pattern: %w", src.Name, err)
+			}
+		default:
+			return fmt.Errorf("source %q: unknown type %q", src.Name, src.Type)
+		}
+
+		if src.Pagination.configured() && src.Type != "" && src.Type != "html" {
+			return fmt.Errorf("source %q: pagination is only supported by html sources", src.Name)
+		}
+		if src.Charset != "" {
+			if enc, _ := charset.Lookup(src.Charset); enc == nil {
+				return fmt.Errorf("source %q: unknown charset %q", src.Name, src.Charset)
+			}
+		}
+		if src.Timezone != "" {
+			if _, err := time.LoadLocation(src.Timezone); err != nil {
+				return fmt.Errorf("source %q: invalid timezone: %w", src.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// location 返回来源配置的时区，未配置时为 UTC
+func (src SourceConfig) location() *time.Location {
+	// timezone 已由 Validate 检查
+	if src.Timezone != "" {
+		if loc, err := time.LoadLocation(src.Timezone); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// IsEnabled 返回该来源是否启用
+func (src SourceConfig) IsEnabled() bool {
+	return src.Enabled == nil || *src.Enabled
+}
+
+// Build 根据配置创建对应的来源
+func (src SourceConfig) Build() Source {
+	switch src.Type {
+	case "feed":
+		feed := NewFeedSource(src.Name, src.FeedURL...)
+		feed.location = src.location()
+		return feed
+	case "sitemap":
+		return newSitemapSource(src)
+	}
+	if src.Pagination.configured() {
+		return paginatedSource{newSelectorSource(src)}
+	}
+	return newSelectorSource(src)
+}
@@ -0,0 +1,276 @@
+package scraper
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// 正文提取参考 Readability 的做法：先去掉明显的模板元素（导航、页脚、广告等），
+// 再按段落文本长度、逗号数量给父节点打分，并用链接密度降权，得分最高的节点即为正文。
+
+var (
+	// unlikelyCandidates 匹配几乎不可能是正文的 class/id
+	unlikelyCandidates = regexp.MustCompile(`(?i)comment|sidebar|footer|footnote|masthead|menu|nav|breadcrumb|share|social|sponsor|promo|related|recommend|newsletter|subscribe|signup|cookie|consent|popup|modal|banner|advert|\bads?\b|ad-|outbrain|taboola|disqus|author-bio|paywall`)
+
+	// maybeCandidate 匹配可能包含正文的 class/id，命中时不做删除
+	maybeCandidate = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow|story|entry|post|text`)
+
+	positiveClass = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|text|blog|story`)
+	negativeClass = regexp.MustCompile(`(?i)hidden|comment|com-|contact|footer|footnote|masthead|meta|outbrain|promo|related|scroll|share|sidebar|skyscraper|sponsor|shopping|tags|tool|widget|ad-|advert`)
+)
+
+// boilerplateTags 直接删除的标签
+const boilerplateTags = "script, style, noscript, iframe, svg, canvas, form, button, input, select, textarea, nav, header, footer, aside, [hidden]"
+
+// blockTags 在提取文本时作为段落边界的标签
+var blockTags = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "main": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "li": true, "dl": true, "dt": true, "dd": true,
+	"blockquote": true, "pre": true, "figure": true, "figcaption": true,
+	"table": true, "tr": true, "hr": true, "br": true,
+}
+
+// extractMainContent 从详情页中提取文章正文，段落之间用空行分隔
+func extractMainContent(doc *goquery.Selection) string {
+	root := doc.Clone()
+	removeBoilerplate(root)
+
+	body := root.Find("body")
+	if body.Length() == 0 {
+		body = root
+	}
+
+	best := topCandidate(body)
+	if best == nil {
+		return blockText(body)
+	}
+
+	// 与得分最高节点相邻、同样得分较高的兄弟节点也属于正文
+	bestScore := best.score
+	threshold := math.Max(10, bestScore*0.2)
+
+	parts := make([]string, 0)
+	for sibling := best.node.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+		if sibling.Type != html.ElementNode {
+			continue
+		}
+
+		sel := goquery.NewDocumentFromNode(sibling).Selection
+		include := sibling == best.node
+		if !include {
+			if score, ok := best.scores[sibling]; ok && score*(1-linkDensity(sel)) >= threshold {
+				include = true
+			} else if sibling.Data == "p" {
+				text := normalizedText(sel)
+				include = utf8.RuneCountInString(text) > 80 && linkDensity(sel) < 0.25
+			}
+		}
+
+		if include {
+			cleanConditionally(sel)
+			if text := blockText(sel); text != "" {
+				parts = append(parts, text)
+			}
+		}
+	}
+
+	return strings.Join(parts, "\n\n")
+}
+
+// cleanContent 清理指定容器中的模板元素后提取文本，用于配置了详情页选择器的来源
+func cleanContent(sel *goquery.Selection) string {
+	root := sel.Clone()
+	removeBoilerplate(root)
+	cleanConditionally(root)
+	return blockText(root)
+}
+
+// removeBoilerplate 删除导航、脚本、广告等不可能是正文的元素
+func removeBoilerplate(root *goquery.Selection) {
+	root.Find(boilerplateTags).Remove()
+
+	root.Find("*").Each(func(_ int, sel *goquery.Selection) {
+		switch goquery.NodeName(sel) {
+		case "html", "body", "article", "main":
+			return
+		}
+
+		match := classAndID(sel)
+		if match != "" && unlikelyCandidates.MatchString(match) && !maybeCandidate.MatchString(match) {
+			sel.Remove()
+		}
+	})
+}
+
+// cleanConditionally 删除正文中链接过多或带有负面 class 的块
+func cleanConditionally(root *goquery.Selection) {
+	root.Find("h1").Remove()
+
+	root.Find("div, section, ul, ol, table, dl").Each(func(_ int, sel *goquery.Selection) {
+		if classWeight(sel) < 0 || linkDensity(sel) > 0.5 {
+			sel.Remove()
+		}
+	})
+}
+
+// candidate 是正文候选节点及其得分
+type candidate struct {
+	node   *html.Node
+	score  float64
+	scores map[*html.Node]float64
+}
+
+// topCandidate 给段落的父节点与祖父节点打分，返回得分最高的节点
+func topCandidate(body *goquery.Selection) *candidate {
+	scores := make(map[*html.Node]float64)
+	order := make([]*html.Node, 0)
+
+	body.Find("p, pre, td, blockquote").Each(func(_ int, p *goquery.Selection) {
+		text := normalizedText(p)
+		length := utf8.RuneCountInString(text)
+		if length < 25 {
+			return
+		}
+
+		commas := strings.Count(text, ",") + strings.Count(text, "，") + strings.Count(text, "。")
+		score := 1 + float64(commas) + math.Min(float64(length)/100, 3)
+
+		for level, ancestor := range []*goquery.Selection{p.Parent(), p.Parent().Parent()} {
+			if ancestor.Length() == 0 {
+				continue
+			}
+
+			node := ancestor.Nodes[0]
+			if _, ok := scores[node]; !ok {
+				scores[node] = classWeight(ancestor) + tagWeight(node.Data)
+				order = append(order, node)
+			}
+
+			if level == 0 {
+				scores[node] += score
+			} else {
+				scores[node] += score / 2
+			}
+		}
+	})
+
+	var best *candidate
+	for _, node := range order {
+		if node.Parent == nil {
+			continue
+		}
+
+		sel := goquery.NewDocumentFromNode(node).Selection
+		score := scores[node] * (1 - linkDensity(sel))
+		if best == nil || score > best.score {
+			best = &candidate{node: node, score: score, scores: scores}
+		}
+	}
+
+	return best
+}
+
+// classWeight 根据 class/id 判断节点是否可能是正文
+func classWeight(sel *goquery.Selection) float64 {
+	var weight float64
+	for _, attr := range []string{"class", "id"} {
+		value, ok := sel.Attr(attr)
+		if !ok || value == "" {
+			continue
+		}
+		if negativeClass.MatchString(value) {
+			weight -= 25
+		}
+		if positiveClass.MatchString(value) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// tagWeight 是不同标签作为正文容器的初始分
+func tagWeight(tag string) float64 {
+	switch tag {
+	case "div", "article", "main", "section":
+		return 5
+	case "pre", "td", "blockquote":
+		return 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		return -3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		return -5
+	}
+	return 0
+}
+
+// linkDensity 返回链接文本占全部文本的比例
+func linkDensity(sel *goquery.Selection) float64 {
+	length := utf8.RuneCountInString(normalizedText(sel))
+	if length == 0 {
+		return 0
+	}
+
+	var linkLength int
+	sel.Find("a").Each(func(_ int, a *goquery.Selection) {
+		linkLength += utf8.RuneCountInString(normalizedText(a))
+	})
+	return float64(linkLength) / float64(length)
+}
+
+// classAndID 返回节点 class 与 id 拼接后的字符串
+func classAndID(sel *goquery.Selection) string {
+	class, _ := sel.Attr("class")
+	id, _ := sel.Attr("id")
+	return strings.TrimSpace(class + " " + id)
+}
+
+// normalizedText 返回合并空白后的节点文本
+func normalizedText(sel *goquery.Selection) string {
+	return strings.Join(strings.Fields(sel.Text()), " ")
+}
+
+// blockText 提取节点文本并保留段落边界，段落之间用空行分隔
+func blockText(sel *goquery.Selection) string {
+	paragraphs := make([]string, 0)
+	var buf strings.Builder
+
+	flush := func() {
+		if text := strings.Join(strings.Fields(buf.String()), " "); text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+		buf.Reset()
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			buf.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			if blockTags[n.Data] {
+				flush()
+				defer flush()
+			}
+		case html.CommentNode:
+			return
+		}
+
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+
+	for _, node := range sel.Nodes {
+		walk(node)
+	}
+	flush()
+
+	return strings.Join(paragraphs, "\n\n")
+}
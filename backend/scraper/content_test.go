@@ -0,0 +1,141 @@
+package scraper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const articlePage = `<html><head><title>Zelda sequel announced</title><script>var ads = [];</script></head>
+<body>
+	<header><nav><a href="/">Home</a> <a href="/news">News</a> <a href="/reviews">Reviews</a></nav></header>
+	<div class="layout">
+		<div class="article-body" id="story">
+			<h1>Zelda sequel announced</h1>
+			<p>Nintendo has announced that the next Legend of Zelda game will launch on Switch 2 in March next year, the company said on Tuesday.</p>
+			<div class="share-tools"><a href="/share/x">Share on X</a> <a href="/share/fb">Share on Facebook</a></div>
+			<p>The publisher showed a first trailer during its Direct presentation, revealing a new open world, a playable Zelda and a co-op mode.</p>
+			<p>Pre-orders open today in North America and Europe, with a collector's edition that includes an art book and a steelbook case.</p>
+		</div>
+		<aside class="sidebar"><p>Most popular: a very long list of other stories that readers might also enjoy reading today, and more.</p></aside>
+		<div class="related-articles"><ul><li><a href="/a">Mario Kart 9 leaks, and what it means for the series</a></li><li><a href="/b">Metroid Prime 4 review, finally here after all these years</a></li></ul></div>
+	</div>
+	<div id="comments"><p>First! This is a comment that is long enough to be scored, but it is not part of the article.</p></div>
+	<footer><p>Copyright Example Media, all rights reserved, including the right to reproduce this page.</p></footer>
+</body></html>`
+
+const chineseArticlePage = `<html><body>
+	<div class="nav"><a href="/">首页</a><a href="/news">新闻</a><a href="/pc">单机</a></div>
+	<div class="Mid2L_con">
+		<p>任天堂宣布《塞尔达传说》系列新作将于明年三月登陆Switch 2平台，官方在周二的直面会上公布了首支预告片。</p>
+		<p>预告片展示了全新的开放世界、可操作的塞尔达公主以及双人合作模式，引发了玩家的热烈讨论。</p>
+		<p>北美和欧洲地区今天开启预购，限定版附赠设定集和铁盒，国内发售日期暂未公布。</p>
+	</div>
+	<div class="recommend"><a href="/1">相关阅读：马力欧赛车新作曝光，系列玩法迎来大改</a></div>
+</body></html>`
+
+func TestExtractMainContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		page    string
+		want    []string
+		notWant []string
+		blocks  int
+	}{
+		{
+			name: "english article",
+			page: articlePage,
+			want: []string{
+				"Nintendo has announced that the next Legend of Zelda game",
+				"The publisher showed a first trailer",
+				"Pre-orders open today",
+			},
+			notWant: []string{"Home", "Share on", "Most popular", "Mario Kart", "First!", "Copyright", "var ads", "Zelda sequel announced\n"},
+			blocks:  3,
+		},
+		{
+			name:    "chinese article",
+			page:    chineseArticlePage,
+			want:    []string{"任天堂宣布《塞尔达传说》系列新作", "预告片展示了全新的开放世界", "北美和欧洲地区今天开启预购"},
+			notWant: []string{"首页", "相关阅读"},
+			blocks:  3,
+		},
+		{
+			name:   "short page without paragraphs",
+			page:   `<html><body><div>Only a line of text</div><script>x()</script></body></html>`,
+			want:   []string{"Only a line of text"},
+			blocks: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.page))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := extractMainContent(doc.Selection)
+
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("content misses %q:\n%s", want, got)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("content contains %q:\n%s", notWant, got)
+				}
+			}
+			if blocks := len(strings.Split(got, "\n\n")); blocks != tt.blocks {
+				t.Errorf("content has %d paragraphs, want %d:\n%s", blocks, tt.blocks, got)
+			}
+		})
+	}
+}
+
+func TestCleanContent(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(articlePage))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := cleanContent(doc.Find("div.article-body"))
+	want := strings.Join([]string{
+		"Nintendo has announced that the next Legend of Zelda game will launch on Switch 2 in March next year, the company said on Tuesday.",
+		"The publisher showed a first trailer during its Direct presentation, revealing a new open world, a playable Zelda and a co-op mode.",
+		"Pre-orders open today in North America and Europe, with a collector's edition that includes an art book and a steelbook case.",
+	}, "\n\n")
+	if got != want {
+		t.Errorf("cleanContent() = %q, want %q", got, want)
+	}
+
+	// 清理作用在副本上，不影响原文档
+	if doc.Find("div.share-tools").Length() != 1 {
+		t.Error("cleanContent() modified the document")
+	}
+}
+
+func TestBlockText(t *testing.T) {
+	tests := []struct {
+		html string
+		want string
+	}{
+		{"<div>One <b>two</b>\n three</div>", "One two three"},
+		{"<div><p>First</p><p>Second</p></div>", "First\n\nSecond"},
+		{"<div>Line<br>break</div>", "Line\n\nbreak"},
+		{"<ul><li>A</li><li>B</li></ul>", "A\n\nB"},
+		{"<div><!-- comment -->Text</div>", "Text"},
+		{"<div>  </div>", ""},
+	}
+
+	for _, tt := range tests {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader("<html><body>" + tt.html + "</body></html>"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := blockText(doc.Find("body").Children().First()); got != tt.want {
+			t.Errorf("blockText(%q) = %q, want %q", tt.html, got, tt.want)
+		}
+	}
+}
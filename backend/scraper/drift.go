@@ -0,0 +1,136 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// 参与漂移检测的文章字段
+const (
+	FieldImage     = "image"
+	FieldSummary   = "summary"
+	FieldPublished = "published"
+)
+
+// driftFields 是需要检查是否突然变空的字段
+var driftFields = []string{FieldImage, FieldSummary, FieldPublished}
+
+const (
+	// minBaselineRuns 是建立基线所需的最少历史运行次数
+	minBaselineRuns = 3
+
+	// dropRatio 产出低于基线的该比例时视为明显下降
+	dropRatio = 0.2
+
+	// fieldBaselineRatio 字段在基线中的填充率达到该比例时，才检查它是否突然变空
+	fieldBaselineRatio = 0.5
+)
+
+// DegradedEvent 表示某个来源的产出相对历史基线明显下降，通常意味着站点改版导致选择器失效
+type DegradedEvent struct {
+	Source     string    `json:"source"`
+	RunID      string    `json:"run_id"`
+	DetectedAt time.Time `json:"detected_at"`
+	Reasons    []string  `json:"reasons"`
+}
+
+// DetectDrift 将本次运行报告与该来源的历史报告比较，返回是否出现了 "source degraded" 事件
+//
+// 以下情况会触发事件：解析出的文章数降为零或远低于基线；基线中大多数文章都有的
+// 图片、摘要、发布时间等字段在本次运行中全部为空。
+func DetectDrift(current SourceReport, history []SourceReport) (DegradedEvent, bool) {
+	event := DegradedEvent{
+		Source:     current.Source,
+		RunID:      current.RunID,
+		DetectedAt: time.Now(),
+		Reasons:    make([]string, 0),
+	}
+
+	// 只用有产出的历史运行建立基线，避免故障期间基线被拉低
+	baselineRuns := 0
+	baselineItems := 0
+	baselineFields := make(map[string]int)
+	for _, run := range history {
+		if run.ItemsParsed == 0 {
+			continue
+		}
+		baselineRuns++
+		baselineItems += run.ItemsParsed
+		for _, field := range driftFields {
+			baselineFields[field] += run.FieldCounts[field]
+		}
+	}
+	// 跳过了未变化的列表页时产出少是正常的
+	if baselineRuns < minBaselineRuns || current.Unchanged > 0 {
+		return event, false
+	}
+
+	average := float64(baselineItems) / float64(baselineRuns)
+	switch {
+	case current.ItemsFound == 0:
+		event.Reasons = append(event.Reasons, fmt.Sprintf("no listing items matched (baseline %.1f articles per run)", average))
+	case current.Incremental:
+		// 增量来源在没有新文章的运行中产出为零是正常的，只检查条目是否还能匹配
+	case current.ItemsParsed == 0:
+		event.Reasons = append(event.Reasons, fmt.Sprintf("%d listing items matched but none parsed (baseline %.1f articles per run)", current.ItemsFound, average))
+	case float64(current.ItemsParsed) < average*dropRatio:
+		event.Reasons = append(event.Reasons, fmt.Sprintf("only %d articles parsed (baseline %.1f articles per run)", current.ItemsParsed, average))
+	}
+
+	if current.ItemsParsed > 0 {
+		for _, field := range driftFields {
+			ratio := float64(baselineFields[field]) / float64(baselineItems)
+			if ratio >= fieldBaselineRatio && current.FieldCounts[field] == 0 {
+				event.Reasons = append(event.Reasons, fmt.Sprintf("%s is empty on all %d articles (baseline %.0f%% filled)", field, current.ItemsParsed, ratio*100))
+			}
+		}
+	}
+
+	return event, len(event.Reasons) > 0
+}
+
+// Notifier 接收来源退化事件，用于接入告警
+type Notifier interface {
+	Notify(event DegradedEvent) error
+}
+
+// WebhookNotifier 以JSON格式将事件POST到指定地址
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookNotifier 创建一个发送到 url 的 WebhookNotifier
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		URL:    url,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (n *WebhookNotifier) Notify(event DegradedEvent) error {
+	payload, err := json.Marshal(struct {
+		Type string `json:"type"`
+		DegradedEvent
+	}{
+		Type:          "source_degraded",
+		DegradedEvent: event,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.Client.Post(n.URL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package scraper
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// healthyRun 是一次所有字段都填充了的正常运行
+func healthyRun(items int) SourceReport {
+	return SourceReport{
+		Source:      "Example",
+		ItemsFound:  items,
+		ItemsParsed: items,
+		FieldCounts: map[string]int{FieldImage: items, FieldSummary: items, FieldPublished: items},
+	}
+}
+
+func TestDetectDrift(t *testing.T) {
+	baseline := []SourceReport{healthyRun(10), healthyRun(10), healthyRun(10)}
+
+	tests := []struct {
+		name    string
+		current SourceReport
+		history []SourceReport
+		reasons []string
+	}{
+		{
+			name:    "healthy run",
+			current: healthyRun(9),
+			history: baseline,
+		},
+		{
+			name:    "baseline needs three productive runs",
+			current: SourceReport{Source: "Example"},
+			history: []SourceReport{healthyRun(10), healthyRun(10), {Source: "Example", Errors: []string{"timeout"}}},
+		},
+		{
+			name:    "no listing items matched",
+			current: SourceReport{Source: "Example"},
+			history: baseline,
+			reasons: []string{"no listing items matched (baseline 10.0 articles per run)"},
+		},
+		{
+			name:    "items matched but none parsed",
+			current: SourceReport{Source: "Example", ItemsFound: 10},
+			history: baseline,
+			reasons: []string{"10 listing items matched but none parsed"},
+		},
+		{
+			name:    "yield below the drop ratio",
+			current: healthyRun(1),
+			history: baseline,
+			reasons: []string{"only 1 articles parsed"},
+		},
+		{
+			name:    "yield at the drop ratio",
+			current: healthyRun(2),
+			history: baseline,
+		},
+		{
+			name: "fields that go empty",
+			current: SourceReport{
+				Source:      "Example",
+				ItemsFound:  10,
+				ItemsParsed: 10,
+				FieldCounts: map[string]int{FieldSummary: 10},
+			},
+			history: baseline,
+			reasons: []string{"image is empty on all 10 articles", "published is empty on all 10 articles"},
+		},
+		{
+			name: "fields rarely filled in the baseline",
+			current: SourceReport{
+				Source:      "Example",
+				ItemsFound:  10,
+				ItemsParsed: 10,
+				FieldCounts: map[string]int{FieldImage: 10, FieldSummary: 10},
+			},
+			history: []SourceReport{
+				{ItemsParsed: 10, FieldCounts: map[string]int{FieldImage: 10, FieldSummary: 10, FieldPublished: 4}},
+				{ItemsParsed: 10, FieldCounts: map[string]int{FieldImage: 10, FieldSummary: 10, FieldPublished: 4}},
+				{ItemsParsed: 10, FieldCounts: map[string]int{FieldImage: 10, FieldSummary: 10, FieldPublished: 4}},
+			},
+		},
+		{
+			name:    "incremental source without new articles",
+			current: SourceReport{Source: "Example", ItemsFound: 10, Incremental: true},
+			history: baseline,
+		},
+		{
+			name:    "incremental source whose items stopped matching",
+			current: SourceReport{Source: "Example", Incremental: true},
+			history: baseline,
+			reasons: []string{"no listing items matched"},
+		},
+		{
+			name:    "unchanged listing pages",
+			current: SourceReport{Source: "Example", Unchanged: 1},
+			history: baseline,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, degraded := DetectDrift(tt.current, tt.history)
+			if degraded != (len(tt.reasons) > 0) || len(event.Reasons) != len(tt.reasons) {
+				t.Fatalf("DetectDrift() = %v, reasons %q, want reasons %q", degraded, event.Reasons, tt.reasons)
+			}
+			for i, reason := range tt.reasons {
+				if !strings.HasPrefix(event.Reasons[i], reason) {
+					t.Errorf("reason %d = %q, want prefix %q", i, event.Reasons[i], reason)
+				}
+			}
+			if event.Source != "Example" {
+				t.Errorf("event source = %q", event.Source)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var contentType string
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	event := DegradedEvent{
+		Source:     "Example",
+		RunID:      "run-1",
+		DetectedAt: time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC),
+		Reasons:    []string{"no listing items matched"},
+	}
+	if err := NewWebhookNotifier(server.URL).Notify(event); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	if contentType != "application/json" {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+	want := map[string]interface{}{
+		"type":        "source_degraded",
+		"source":      "Example",
+		"run_id":      "run-1",
+		"detected_at": "2024-03-05T10:30:00Z",
+	}
+	for key, value := range want {
+		if payload[key] != value {
+			t.Errorf("payload[%q] = %v, want %v", key, payload[key], value)
+		}
+	}
+	if reasons, _ := payload["reasons"].([]interface{}); len(reasons) != 1 || reasons[0] != "no listing items matched" {
+		t.Errorf("payload reasons = %v", payload["reasons"])
+	}
+}
+
+func TestWebhookNotifierErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+
+	notifier := NewWebhookNotifier(server.URL)
+	if err := notifier.Notify(DegradedEvent{Source: "Example"}); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Notify() to a failing webhook error = %v", err)
+	}
+
+	server.Close()
+	if err := notifier.Notify(DegradedEvent{Source: "Example"}); err == nil {
+		t.Error("Notify() to a closed webhook succeeded")
+	}
+}
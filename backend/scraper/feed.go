@@ -0,0 +1,307 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly/v2"
+	"golang.org/x/net/html/charset"
+)
+
+// FeedSource 通过RSS/Atom订阅源抓取新闻，比CSS选择器稳定得多
+type FeedSource struct {
+	name     string
+	feedURLs []string
+
+	// location 解析不带时区的日期时使用的时区
+	location *time.Location
+}
+
+// NewFeedSource 创建一个基于订阅源的来源，可传入多个RSS或Atom地址，不带时区的日期按 UTC 解析
+func NewFeedSource(name string, feedURLs ...string) *FeedSource {
+	return &FeedSource{
+		name:     name,
+		feedURLs: feedURLs,
+		location: time.UTC,
+	}
+}
+
+func (f *FeedSource) Name() string {
+	return f.name
+}
+
+func (f *FeedSource) Discover() []string {
+	return f.feedURLs
+}
+
+func (f *FeedSource) ParseListing(c *colly.Collector, out Emitter) {
+	c.OnResponse(func(r *colly.Response) {
+		if skipped(r) {
+			return
+		}
+		items, err := parseFeed(r.Body, f.location)
+		if err != nil {
+			out.Error(fmt.Errorf("%s: parse feed: %w", r.Request.URL, err))
+			return
+		}
+		out.Found(len(items))
+
+		for _, item := range items {
+			if item.title == "" || item.link == "" {
+				continue
+			}
+
+			link := r.Request.AbsoluteURL(item.link)
+			image := item.image
+			if image != "" {
+				image = r.Request.AbsoluteURL(image)
+			}
+
+			article := newArticle(item.title, link, image, item.summary, f.name)
+			article.ListingSnapshot = requestSnapshot(r.Request)
+			article.Author = item.author
+			if !item.published.IsZero() {
+				article.PublishedAt = item.published
+			}
+			out.Emit(article)
+		}
+	})
+}
+
+func (f *FeedSource) ParseDetail(c *colly.Collector, emit func(content string)) {
+	parseDefaultDetail(c, emit)
+}
+
+// feedItem 是从RSS或Atom条目中提取出的通用字段
+type feedItem struct {
+	title     string
+	link      string
+	summary   string
+	author    string
+	image     string
+	published time.Time
+}
+
+// feedDocument 同时兼容 RSS 2.0、RSS 1.0 (RDF) 与 Atom 的文档结构
+type feedDocument struct {
+	Channel struct {
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items   []rssItem   `xml:"item"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	Description string `xml:"description"`
+	Encoded     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PubDate     string `xml:"pubDate"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Author      string `xml:"author"`
+	Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Enclosures  []struct {
+		URL  string `xml:"url,attr"`
+		Type string `xml:"type,attr"`
+	} `xml:"enclosure"`
+	MediaContent []struct {
+		URL    string `xml:"url,attr"`
+		Medium string `xml:"medium,attr"`
+		Type   string `xml:"type,attr"`
+	} `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaThumbnail []struct {
+		URL string `xml:"url,attr"`
+	} `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+type atomEntry struct {
+	Title string `xml:"title"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+		Type string `xml:"type,attr"`
+	} `xml:"link"`
+	Summary   string `xml:"summary"`
+	Content   string `xml:"content"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+	Authors   []struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	MediaThumbnail []struct {
+		URL string `xml:"url,attr"`
+	} `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+// parseFeed 解析RSS或Atom文档，返回其中的条目，不带时区的日期按 loc 解析
+func parseFeed(body []byte, loc *time.Location) ([]feedItem, error) {
+	var doc feedDocument
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = charset.NewReaderLabel
+	decoder.Strict = false
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	items := make([]feedItem, 0)
+	for _, item := range append(doc.Channel.Items, doc.Items...) {
+		items = append(items, item.toFeedItem(loc))
+	}
+	for _, entry := range doc.Entries {
+		items = append(items, entry.toFeedItem(loc))
+	}
+	return items, nil
+}
+
+func (item rssItem) toFeedItem(loc *time.Location) feedItem {
+	link := strings.TrimSpace(item.Link)
+	if link == "" && strings.HasPrefix(item.GUID, "http") {
+		link = strings.TrimSpace(item.GUID)
+	}
+
+	author := firstNonEmpty(item.Creator, item.Author)
+
+	published, _ := parseDate(firstNonEmpty(item.PubDate, item.Date), loc)
+
+	// 图片优先级：图片类型的附件 > media:content > media:thumbnail > 描述中的第一张图
+	var image string
+	for _, enclosure := range item.Enclosures {
+		if strings.HasPrefix(enclosure.Type, "image/") {
+			image = enclosure.URL
+			break
+		}
+	}
+	if image == "" {
+		for _, media := range item.MediaContent {
+			if media.Medium == "image" || strings.HasPrefix(media.Type, "image/") {
+				image = media.URL
+				break
+			}
+		}
+	}
+	if image == "" && len(item.MediaThumbnail) > 0 {
+		image = item.MediaThumbnail[0].URL
+	}
+
+	summary, descImage := htmlText(item.Description)
+	if image == "" {
+		image = descImage
+	}
+	if image == "" {
+		_, image = htmlText(item.Encoded)
+	}
+
+	return feedItem{
+		title:     strings.TrimSpace(item.Title),
+		link:      link,
+		summary:   summary,
+		author:    strings.TrimSpace(author),
+		image:     strings.TrimSpace(image),
+		published: published,
+	}
+}
+
+func (entry atomEntry) toFeedItem(loc *time.Location) feedItem {
+	var link string
+	for _, l := range entry.Links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			link = l.Href
+			break
+		}
+	}
+
+	var author string
+	if len(entry.Authors) > 0 {
+		author = entry.Authors[0].Name
+	}
+
+	published, _ := parseDate(firstNonEmpty(entry.Published, entry.Updated), loc)
+
+	var image string
+	for _, l := range entry.Links {
+		if l.Rel == "enclosure" && strings.HasPrefix(l.Type, "image/") {
+			image = l.Href
+			break
+		}
+	}
+	if image == "" && len(entry.MediaThumbnail) > 0 {
+		image = entry.MediaThumbnail[0].URL
+	}
+
+	summary, summaryImage := htmlText(firstNonEmpty(entry.Summary, entry.Content))
+	if image == "" {
+		image = summaryImage
+	}
+
+	return feedItem{
+		title:     strings.TrimSpace(entry.Title),
+		link:      strings.TrimSpace(link),
+		summary:   summary,
+		author:    strings.TrimSpace(author),
+		image:     strings.TrimSpace(image),
+		published: published,
+	}
+}
+
+// htmlText 去掉订阅源描述中的HTML标签，同时返回其中第一张图片的地址
+func htmlText(fragment string) (string, string) {
+	if strings.TrimSpace(fragment) == "" {
+		return "", ""
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(fragment))
+	if err != nil {
+		return strings.TrimSpace(fragment), ""
+	}
+
+	image := imageSource(doc.Find("img"))
+	return strings.Join(strings.Fields(doc.Text()), " "), image
+}
+
+// dateLayouts 是订阅源和网页中常见的日期格式
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// parseDate 按常见格式依次尝试解析日期，不带时区的日期按 loc 解析，loc 为 nil 时使用 UTC
+func parseDate(value string, loc *time.Location) (time.Time, bool) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// firstNonEmpty 返回第一个非空字符串
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,262 @@
+package scraper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+func TestParseFeed(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []feedItem
+	}{
+		{
+			name: "rss 2.0",
+			body: `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:media="http://search.yahoo.com/mrss/"
+	xmlns:content="http://purl.org/rss/1.0/modules/content/">
+<channel>
+	<title>Game News</title>
+	<item>
+		<title> Zelda sequel announced </title>
+		<link>https://example.com/zelda</link>
+		<description><![CDATA[<p>Nintendo <b>confirmed</b> it.</p><img src="https://example.com/desc.jpg">]]></description>
+		<pubDate>Tue, 05 Mar 2024 10:30:00 +0000</pubDate>
+		<dc:creator>Jane Doe</dc:creator>
+		<media:content url="https://example.com/video.mp4" medium="video"/>
+		<media:content url="https://example.com/media.jpg" medium="image"/>
+	</item>
+	<item>
+		<title>Link from guid</title>
+		<guid>https://example.com/guid</guid>
+		<description>Plain text</description>
+		<enclosure url="https://example.com/podcast.mp3" type="audio/mpeg"/>
+		<enclosure url="https://example.com/enclosure.png" type="image/png"/>
+		<author>news@example.com (News Desk)</author>
+		<dc:date>2024-03-05T08:00:00Z</dc:date>
+	</item>
+	<item>
+		<title>Image from content</title>
+		<link>https://example.com/content</link>
+		<guid isPermaLink="false">tag:example.com,2024:3</guid>
+		<content:encoded><![CDATA[<p>Body</p><img data-src="https://example.com/encoded.jpg" src="https://example.com/spacer.gif">]]></content:encoded>
+	</item>
+</channel>
+</rss>`,
+			want: []feedItem{
+				{
+					title:     "Zelda sequel announced",
+					link:      "https://example.com/zelda",
+					summary:   "Nintendo confirmed it.",
+					author:    "Jane Doe",
+					image:     "https://example.com/media.jpg",
+					published: time.Date(2024, time.March, 5, 10, 30, 0, 0, time.UTC),
+				},
+				{
+					title:     "Link from guid",
+					link:      "https://example.com/guid",
+					summary:   "Plain text",
+					author:    "news@example.com (News Desk)",
+					image:     "https://example.com/enclosure.png",
+					published: time.Date(2024, time.March, 5, 8, 0, 0, 0, time.UTC),
+				},
+				{
+					title: "Image from content",
+					link:  "https://example.com/content",
+					image: "https://example.com/encoded.jpg",
+				},
+			},
+		},
+		{
+			name: "rss 1.0",
+			body: `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/"
+	xmlns:dc="http://purl.org/dc/elements/1.1/">
+	<channel><title>Game News</title></channel>
+	<item>
+		<title>RDF item</title>
+		<link>https://example.com/rdf</link>
+		<dc:date>2024-03-05T09:00:00+09:00</dc:date>
+	</item>
+</rdf:RDF>`,
+			want: []feedItem{
+				{
+					title:     "RDF item",
+					link:      "https://example.com/rdf",
+					published: time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name: "atom",
+			body: `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/">
+	<title>Game News</title>
+	<entry>
+		<title>Atom entry</title>
+		<link rel="replies" href="https://example.com/atom#comments"/>
+		<link rel="alternate" type="text/html" href="https://example.com/atom"/>
+		<link rel="enclosure" type="image/jpeg" href="https://example.com/atom.jpg"/>
+		<summary type="html">&lt;p&gt;Short &lt;em&gt;summary&lt;/em&gt;&lt;/p&gt;</summary>
+		<content type="html">&lt;p&gt;Full content&lt;/p&gt;</content>
+		<published>2024-03-05T10:30:00Z</published>
+		<updated>2024-03-06T10:30:00Z</updated>
+		<author><name>Jane Doe</name></author>
+	</entry>
+	<entry>
+		<title>Updated only</title>
+		<link href="https://example.com/updated"/>
+		<content type="html">&lt;img src="https://example.com/content.jpg"&gt;Content only</content>
+		<updated>2024-03-06T10:30:00Z</updated>
+		<media:thumbnail url="https://example.com/thumb.jpg"/>
+	</entry>
+</feed>`,
+			want: []feedItem{
+				{
+					title:     "Atom entry",
+					link:      "https://example.com/atom",
+					summary:   "Short summary",
+					author:    "Jane Doe",
+					image:     "https://example.com/atom.jpg",
+					published: time.Date(2024, time.March, 5, 10, 30, 0, 0, time.UTC),
+				},
+				{
+					title:     "Updated only",
+					link:      "https://example.com/updated",
+					summary:   "Content only",
+					image:     "https://example.com/thumb.jpg",
+					published: time.Date(2024, time.March, 6, 10, 30, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name: "no items",
+			body: `<rss version="2.0"><channel><title>Empty</title></channel></rss>`,
+			want: []feedItem{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFeed([]byte(tt.body), time.UTC)
+			if err != nil {
+				t.Fatalf("parseFeed() error = %v", err)
+			}
+			assertFeedItems(t, got, tt.want)
+		})
+	}
+}
+
+func TestParseFeedCharset(t *testing.T) {
+	body, err := traditionalchinese.Big5.NewEncoder().Bytes([]byte(`<?xml version="1.0" encoding="big5"?>
+<rss version="2.0"><channel><item><title>巴哈姆特電玩資訊站</title><link>https://example.com/gnn</link></item></channel></rss>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := parseFeed(body, time.UTC)
+	if err != nil {
+		t.Fatalf("parseFeed() error = %v", err)
+	}
+	assertFeedItems(t, got, []feedItem{{title: "巴哈姆特電玩資訊站", link: "https://example.com/gnn"}})
+}
+
+func TestParseFeedInvalid(t *testing.T) {
+	if _, err := parseFeed([]byte("not a feed"), time.UTC); err == nil {
+		t.Error("parseFeed() error = nil, want an error")
+	}
+}
+
+func assertFeedItems(t *testing.T, got, want []feedItem) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d items %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		gotItem, wantItem := got[i], want[i]
+		samePublished := gotItem.published.Equal(wantItem.published)
+		gotItem.published, wantItem.published = time.Time{}, time.Time{}
+		if gotItem != wantItem || !samePublished {
+			t.Errorf("item %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+		ok    bool
+	}{
+		{"Tue, 05 Mar 2024 10:30:00 +0000", time.Date(2024, time.March, 5, 10, 30, 0, 0, time.UTC), true},
+		{"Tue, 5 Mar 2024 18:30:00 +0800", time.Date(2024, time.March, 5, 10, 30, 0, 0, time.UTC), true},
+		{"Tue, 05 Mar 2024 10:30:00 GMT", time.Date(2024, time.March, 5, 10, 30, 0, 0, time.UTC), true},
+		{"2024-03-05T10:30:00Z", time.Date(2024, time.March, 5, 10, 30, 0, 0, time.UTC), true},
+		{"2024-03-05T10:30:00.123+01:00", time.Date(2024, time.March, 5, 9, 30, 0, 123000000, time.UTC), true},
+		{"2024-03-05T10:30+00:00", time.Date(2024, time.March, 5, 10, 30, 0, 0, time.UTC), true},
+		{" 2024-03-05 ", time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC), true},
+		{"", time.Time{}, false},
+		{"yesterday", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseDate(tt.value, nil)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("parseDate(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseDateLocation(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		// 不带时区的日期按来源的时区解析
+		{"2024-03-05 18:30:00", time.Date(2024, time.March, 5, 10, 30, 0, 0, time.UTC)},
+		{"2024-03-05", time.Date(2024, time.March, 4, 16, 0, 0, 0, time.UTC)},
+		// 带时区的日期不受影响
+		{"Tue, 05 Mar 2024 10:30:00 +0000", time.Date(2024, time.March, 5, 10, 30, 0, 0, time.UTC)},
+		{"2024-03-05T10:30:00Z", time.Date(2024, time.March, 5, 10, 30, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseDate(tt.value, shanghai)
+		if !ok || !got.Equal(tt.want) {
+			t.Errorf("parseDate(%q, Asia/Shanghai) = %v, %v, want %v", tt.value, got, ok, tt.want)
+		}
+	}
+}
+
+func TestFeedSourceTimezone(t *testing.T) {
+	if _, err := time.LoadLocation("Asia/Shanghai"); err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<rss version="2.0"><channel><item><title>新作公布</title><link>https://example.com/news/1</link>` +
+			`<pubDate>2024-03-05 18:30:00</pubDate></item></channel></rss>`))
+	}))
+	defer server.Close()
+
+	s := NewScraperWithConfig(&Config{
+		Politeness: PolitenessConfig{Default: DomainPolicy{Parallelism: 2}},
+		Sources:    []SourceConfig{{Name: "Example", Type: "feed", FeedURL: StringList{server.URL}, Timezone: "Asia/Shanghai"}},
+	})
+	articles, _, err := s.ScrapeGamesWithReports()
+	if err != nil || len(articles) != 1 {
+		t.Fatalf("ScrapeGamesWithReports() = %d articles, %v", len(articles), err)
+	}
+	if want := time.Date(2024, time.March, 5, 10, 30, 0, 0, time.UTC); !articles[0].PublishedAt.Equal(want) {
+		t.Errorf("published = %v, want %v", articles[0].PublishedAt, want)
+	}
+}
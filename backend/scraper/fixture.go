@@ -0,0 +1,83 @@
+package scraper
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// fixtureTransport 从本地目录读取页面而不访问网络
+//
+// URL 按 <dir>/<域名>/<路径> 映射到文件，依次尝试路径本身、加 .html 后缀以及
+// 目录下的 index.html，查询参数会被忽略。找不到文件时返回 404，
+// 与真实站点缺页时的行为一致。
+type fixtureTransport struct {
+	dir string
+}
+
+func newFixtureTransport(dir string) *fixtureTransport {
+	return &fixtureTransport{dir: dir}
+}
+
+func (t *fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for _, candidate := range t.candidates(req) {
+		info, err := os.Stat(candidate)
+		if err != nil || info.IsDir() {
+			continue
+		}
+
+		body, err := os.ReadFile(candidate)
+		if err != nil {
+			return nil, err
+		}
+		return fixtureResponse(req, http.StatusOK, fixtureContentType(candidate), body), nil
+	}
+
+	return fixtureResponse(req, http.StatusNotFound, "text/plain; charset=utf-8", []byte("fixture not found")), nil
+}
+
+// candidates 返回URL可能对应的文件，按优先级排列
+func (t *fixtureTransport) candidates(req *http.Request) []string {
+	host := strings.TrimPrefix(strings.ToLower(req.URL.Hostname()), "www.")
+
+	// path.Clean 会去掉 ".."，保证结果不会跑出 fixture 目录
+	clean := strings.TrimPrefix(path.Clean("/"+req.URL.Path), "/")
+	base := filepath.Join(t.dir, host, filepath.FromSlash(clean))
+
+	if clean == "" {
+		return []string{filepath.Join(base, "index.html")}
+	}
+	return []string{base, base + ".html", filepath.Join(base, "index.html")}
+}
+
+// fixtureContentType 按扩展名推断内容类型，未知时按HTML处理
+func fixtureContentType(name string) string {
+	if contentType := mime.TypeByExtension(filepath.Ext(name)); contentType != "" {
+		return contentType
+	}
+	return "text/html; charset=utf-8"
+}
+
+func fixtureResponse(req *http.Request, status int, contentType string, body []byte) *http.Response {
+	header := make(http.Header)
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+
+	return &http.Response{
+		Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,309 @@
+package scraper
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// cacheStatusHeader 标记正文来自磁盘缓存的响应，值为 cacheUnchanged 表示服务器返回了 304
+const cacheStatusHeader = "X-Scraper-Cache"
+
+// cacheUnchanged 表示页面自缓存以来没有变化
+const cacheUnchanged = "unchanged"
+
+// cachedAtHeader 是缓存条目保存的时间，即页面最近一次完整下载的时间
+const cachedAtHeader = "X-Scraper-Cached-At"
+
+// skippedContextKey 是请求上下文中标记页面跳过解析的键
+const skippedContextKey = "cache_skipped"
+
+// skipUnchanged 在列表页 collector 上注册回调，跳过解析服务器确认未变化、
+// 并且在来源上一次入库的抓取开始之前就已下载过的页面：其中的文章都已入库，
+// 不再解析也就不会再抓取它们的详情页。之后才下载的页面即使未变化也照常解析，
+// 这样入库失败或被中断的一轮抓取到的文章会在下一轮重新提交。
+//
+// 必须在来源注册解析回调之前调用，解析回调用 skipped 检查。
+func (s *Scraper) skipUnchanged(c *colly.Collector, run *sourceRun) {
+	source := run.source.Name()
+	c.OnResponse(func(r *colly.Response) {
+		if unchangedSince(r, s.LastRun(source)) {
+			r.Ctx.Put(skippedContextKey, "1")
+			run.unchanged()
+		}
+	})
+}
+
+// skipped 判断页面是否因为未变化而跳过解析
+func skipped(r *colly.Response) bool {
+	return r.Ctx != nil && r.Ctx.Get(skippedContextKey) != ""
+}
+
+// unchangedSince 判断响应是否是服务器确认未变化、并且在 t 之前就已完整下载过的页面
+func unchangedSince(r *colly.Response, t time.Time) bool {
+	if t.IsZero() || r.Headers == nil || r.Headers.Get(cacheStatusHeader) != cacheUnchanged {
+		return false
+	}
+	cachedAt, err := time.Parse(time.RFC3339Nano, r.Headers.Get(cachedAtHeader))
+	return err == nil && cachedAt.Before(t)
+}
+
+// DefaultCacheMaxAge 是缓存条目在没有被使用的情况下保留的默认时长
+const DefaultCacheMaxAge = 30 * 24 * time.Hour
+
+// cacheTransport 是基于磁盘的HTTP缓存
+//
+// 保存响应的 ETag / Last-Modified，下次请求时带上 If-None-Match / If-Modified-Since，
+// 服务器返回 304 时直接使用缓存的内容，从而减少带宽并降低被限流的可能。
+// 这样的响应带有 cacheStatusHeader 标记，定时抓取据此跳过已经入库过的列表页，见 skipUnchanged。
+type cacheTransport struct {
+	base http.RoundTripper
+	dir  string
+
+	// maxAge 条目超过这个时长没有被使用（写入或命中 304）时由 prune 删除
+	maxAge time.Duration
+}
+
+func newCacheTransport(base http.RoundTripper, dir string, maxAge time.Duration) *cacheTransport {
+	if maxAge <= 0 {
+		maxAge = DefaultCacheMaxAge
+	}
+	return &cacheTransport{base: base, dir: dir, maxAge: maxAge}
+}
+
+// cacheEntry 是缓存条目的元信息，正文单独保存
+type cacheEntry struct {
+	URL          string      `json:"url"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"last_modified,omitempty"`
+	Header       http.Header `json:"header"`
+	StoredAt     time.Time   `json:"stored_at"`
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.base.RoundTrip(req)
+	}
+
+	key := t.key(req.URL.String())
+	entry, cached := t.load(key)
+
+	if cached {
+		req = req.Clone(req.Context())
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// 未变化：使用缓存的正文构造一个 200 响应，并标记为未变化
+	if cached && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+
+		body, err := os.ReadFile(t.bodyPath(key))
+		if err != nil {
+			return t.base.RoundTrip(stripConditional(req))
+		}
+		// 记录最近一次使用的时间，仍在使用的条目不会被清理
+		now := time.Now()
+		os.Chtimes(t.metaPath(key), now, now)
+		return cachedResponse(req, entry, body), nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		// 页面不再提供校验信息，旧条目的校验值已经过时，
+		// 留着它会让之后的请求带上旧的校验值，并在 304 时得到过期的正文
+		if cached {
+			t.remove(key)
+		}
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	entry = cacheEntry{
+		URL:          req.URL.String(),
+		ETag:         etag,
+		LastModified: lastModified,
+		Header:       resp.Header.Clone(),
+		StoredAt:     time.Now(),
+	}
+	if err := t.store(key, entry, body); err != nil {
+		log.Printf("Failed to cache %s: %v", req.URL, err)
+	}
+
+	return resp, nil
+}
+
+// cachedResponse 用缓存内容构造响应，并标记为未变化
+func cachedResponse(req *http.Request, entry cacheEntry, body []byte) *http.Response {
+	header := entry.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	header.Set(cacheStatusHeader, cacheUnchanged)
+	header.Set(cachedAtHeader, entry.StoredAt.Format(time.RFC3339Nano))
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// stripConditional 去掉条件请求头，用于缓存正文丢失时重新完整抓取
+func stripConditional(req *http.Request) *http.Request {
+	req = req.Clone(req.Context())
+	req.Header.Del("If-None-Match")
+	req.Header.Del("If-Modified-Since")
+	return req
+}
+
+// key 返回URL对应的缓存文件名
+func (t *cacheTransport) key(u string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(u)))
+}
+
+func (t *cacheTransport) metaPath(key string) string {
+	return filepath.Join(t.dir, key[:2], key+".json")
+}
+
+func (t *cacheTransport) bodyPath(key string) string {
+	return filepath.Join(t.dir, key[:2], key+".body")
+}
+
+func (t *cacheTransport) load(key string) (cacheEntry, bool) {
+	var entry cacheEntry
+
+	data, err := os.ReadFile(t.metaPath(key))
+	if err != nil {
+		return entry, false
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return entry, false
+	}
+	return entry, true
+}
+
+// store 先写正文再写元信息，避免元信息指向不完整的正文
+func (t *cacheTransport) store(key string, entry cacheEntry, body []byte) error {
+	if err := os.MkdirAll(filepath.Join(t.dir, key[:2]), 0o755); err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(t.bodyPath(key), body); err != nil {
+		return err
+	}
+
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(t.metaPath(key), meta)
+}
+
+// remove 删除缓存条目，先删元信息，之后不会再有请求指向将被删除的正文
+func (t *cacheTransport) remove(key string) {
+	if err := os.Remove(t.metaPath(key)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to remove cache entry %s: %v", key, err)
+		return
+	}
+	os.Remove(t.bodyPath(key))
+}
+
+// writeFileAtomic 先写临时文件再重命名，避免并发读取到写了一半的文件
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// prune 删除超过 maxAge 没有被使用的缓存条目以及写入中断留下的临时文件，返回删除的条目数
+func (t *cacheTransport) prune() (int, error) {
+	cutoff := time.Now().Add(-t.maxAge)
+	removed := 0
+
+	err := filepath.WalkDir(t.dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			return nil
+		}
+
+		name := d.Name()
+		switch {
+		case strings.Contains(name, ".tmp"):
+			os.Remove(path)
+		case strings.HasSuffix(name, ".json"):
+			// 先删元信息，之后不会再有请求指向将被删除的正文
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			os.Remove(strings.TrimSuffix(path, ".json") + ".body")
+			removed++
+		case strings.HasSuffix(name, ".body"):
+			// 元信息写入失败留下的正文
+			if _, err := os.Stat(strings.TrimSuffix(path, ".body") + ".json"); os.IsNotExist(err) {
+				os.Remove(path)
+			}
+		}
+		return nil
+	})
+	return removed, err
+}
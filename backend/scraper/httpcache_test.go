@@ -0,0 +1,271 @@
+package scraper
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// validatorSite 提供一个带 ETag 的页面，请求带上匹配的 If-None-Match 时返回 304
+type validatorSite struct {
+	mu       sync.Mutex
+	etag     string
+	body     string
+	requests []http.Header
+}
+
+func newValidatorSite(t *testing.T, etag, body string) (*validatorSite, *httptest.Server) {
+	site := &validatorSite{etag: etag, body: body}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		site.mu.Lock()
+		defer site.mu.Unlock()
+		site.requests = append(site.requests, r.Header.Clone())
+
+		if site.etag != "" {
+			if r.Header.Get("If-None-Match") == site.etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", site.etag)
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(site.body))
+	}))
+	t.Cleanup(server.Close)
+	return site, server
+}
+
+func (s *validatorSite) set(etag, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.etag, s.body = etag, body
+}
+
+func (s *validatorSite) lastRequest() http.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[len(s.requests)-1]
+}
+
+// fetch 通过 transport 请求 url，返回响应和正文
+func fetch(t *testing.T, transport http.RoundTripper, url string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(body)
+}
+
+func TestCacheTransportConditionalRequests(t *testing.T) {
+	site, server := newValidatorSite(t, `"v1"`, "first version")
+	cache := newCacheTransport(http.DefaultTransport, t.TempDir(), 0)
+	url := server.URL + "/news"
+
+	resp, body := fetch(t, cache, url)
+	if resp.StatusCode != http.StatusOK || body != "first version" || resp.Header.Get(cacheStatusHeader) != "" {
+		t.Fatalf("first response = %d %q, cache status %q", resp.StatusCode, body, resp.Header.Get(cacheStatusHeader))
+	}
+
+	// 服务器返回 304，正文来自缓存并标记为未变化
+	resp, body = fetch(t, cache, url)
+	if got := site.lastRequest().Get("If-None-Match"); got != `"v1"` {
+		t.Errorf("If-None-Match = %q, want %q", got, `"v1"`)
+	}
+	if resp.StatusCode != http.StatusOK || body != "first version" || resp.Header.Get(cacheStatusHeader) != cacheUnchanged {
+		t.Errorf("revalidated response = %d %q, cache status %q", resp.StatusCode, body, resp.Header.Get(cacheStatusHeader))
+	}
+	if _, err := time.Parse(time.RFC3339Nano, resp.Header.Get(cachedAtHeader)); err != nil {
+		t.Errorf("cached-at header = %q: %v", resp.Header.Get(cachedAtHeader), err)
+	}
+
+	// 页面更新后保存新的校验值
+	site.set(`"v2"`, "second version")
+	if _, body = fetch(t, cache, url); body != "second version" {
+		t.Errorf("changed page body = %q", body)
+	}
+	fetch(t, cache, url)
+	if got := site.lastRequest().Get("If-None-Match"); got != `"v2"` {
+		t.Errorf("If-None-Match after an update = %q, want %q", got, `"v2"`)
+	}
+
+	// 不再提供校验信息的页面删除旧条目，之后不再发送旧的校验值
+	site.set("", "third version")
+	if _, body = fetch(t, cache, url); body != "third version" {
+		t.Errorf("page without validators body = %q", body)
+	}
+	if _, cached := cache.load(cache.key(url)); cached {
+		t.Error("stale cache entry was kept")
+	}
+	site.set(`"v2"`, "fourth version")
+	resp, body = fetch(t, cache, url)
+	if got := site.lastRequest().Get("If-None-Match"); got != "" {
+		t.Errorf("If-None-Match after the entry was removed = %q, want none", got)
+	}
+	if body != "fourth version" || resp.Header.Get(cacheStatusHeader) != "" {
+		t.Errorf("response after the entry was removed = %q, cache status %q", body, resp.Header.Get(cacheStatusHeader))
+	}
+}
+
+func TestCacheTransportLastModified(t *testing.T) {
+	lastModified := "Tue, 05 Mar 2024 10:30:00 GMT"
+	var ifModifiedSince []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ifModifiedSince = append(ifModifiedSince, r.Header.Get("If-Modified-Since"))
+		if r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Last-Modified", lastModified)
+		w.Write([]byte("page"))
+	}))
+	defer server.Close()
+
+	cache := newCacheTransport(http.DefaultTransport, t.TempDir(), 0)
+	fetch(t, cache, server.URL)
+	resp, body := fetch(t, cache, server.URL)
+
+	if len(ifModifiedSince) != 2 || ifModifiedSince[0] != "" || ifModifiedSince[1] != lastModified {
+		t.Errorf("If-Modified-Since = %q", ifModifiedSince)
+	}
+	if body != "page" || resp.Header.Get(cacheStatusHeader) != cacheUnchanged {
+		t.Errorf("revalidated response = %q, cache status %q", body, resp.Header.Get(cacheStatusHeader))
+	}
+}
+
+func TestCacheTransportMissingBody(t *testing.T) {
+	site, server := newValidatorSite(t, `"v1"`, "page")
+	cache := newCacheTransport(http.DefaultTransport, t.TempDir(), 0)
+	url := server.URL + "/news"
+
+	fetch(t, cache, url)
+	if err := os.Remove(cache.bodyPath(cache.key(url))); err != nil {
+		t.Fatal(err)
+	}
+
+	// 正文丢失时不带校验值重新完整抓取
+	resp, body := fetch(t, cache, url)
+	if got := site.lastRequest().Get("If-None-Match"); got != "" {
+		t.Errorf("If-None-Match of the refetch = %q, want none", got)
+	}
+	if body != "page" || resp.Header.Get(cacheStatusHeader) != "" {
+		t.Errorf("refetched response = %q, cache status %q", body, resp.Header.Get(cacheStatusHeader))
+	}
+}
+
+func TestCacheTransportPrune(t *testing.T) {
+	dir := t.TempDir()
+	cache := newCacheTransport(http.DefaultTransport, dir, time.Hour)
+	old := time.Now().Add(-2 * time.Hour)
+
+	store := func(url string, modified time.Time) string {
+		key := cache.key(url)
+		if err := cache.store(key, cacheEntry{URL: url, ETag: `"v1"`, StoredAt: modified}, []byte("body")); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(cache.metaPath(key), modified, modified)
+		os.Chtimes(cache.bodyPath(key), modified, modified)
+		return key
+	}
+	stale := store("https://example.com/stale", old)
+	fresh := store("https://example.com/fresh", time.Now())
+
+	// 元信息写入失败留下的正文和中断的临时文件
+	orphan := cache.key("https://example.com/orphan")
+	os.MkdirAll(filepath.Dir(cache.bodyPath(orphan)), 0o755)
+	os.WriteFile(cache.bodyPath(orphan), []byte("body"), 0o644)
+	os.Chtimes(cache.bodyPath(orphan), old, old)
+	tmp := cache.metaPath(fresh) + ".tmp123"
+	os.WriteFile(tmp, nil, 0o644)
+	os.Chtimes(tmp, old, old)
+
+	removed, err := cache.prune()
+	if err != nil {
+		t.Fatalf("prune() error = %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("prune() removed %d entries, want 1", removed)
+	}
+
+	for _, path := range []string{cache.metaPath(stale), cache.bodyPath(stale), cache.bodyPath(orphan), tmp} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed", filepath.Base(path))
+		}
+	}
+	for _, path := range []string{cache.metaPath(fresh), cache.bodyPath(fresh)} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s was removed", filepath.Base(path))
+		}
+	}
+
+	// 缓存目录不存在时没有可清理的条目
+	if removed, err := newCacheTransport(http.DefaultTransport, filepath.Join(dir, "missing"), 0).prune(); removed != 0 || err != nil {
+		t.Errorf("prune() of a missing directory = %d, %v", removed, err)
+	}
+}
+
+const cachedListingPage = `<html><body>
+	<div class="item"><a href="/news/zelda">Zelda sequel announced</a></div>
+	<div class="item"><a href="/news/cs2">Counter-Strike 2 update</a></div>
+</body></html>`
+
+func TestScrapeSkipsUnchangedListings(t *testing.T) {
+	site, server := newValidatorSite(t, `"v1"`, cachedListingPage)
+	s := NewScraperWithConfig(&Config{
+		CacheDir:   t.TempDir(),
+		Politeness: PolitenessConfig{Default: DomainPolicy{Parallelism: 2}},
+		Sources: []SourceConfig{{
+			Name:       "Example",
+			ListingURL: StringList{server.URL + "/news"},
+			Selectors:  SelectorConfig{Item: "div.item", Title: StringList{"a"}, Link: StringList{"a"}},
+		}},
+	})
+
+	scrape := func() ([]Article, SourceReport) {
+		t.Helper()
+		articles, reports, err := s.ScrapeGamesWithReports()
+		if err != nil || len(reports) != 1 {
+			t.Fatalf("ScrapeGamesWithReports() = %d reports, %v", len(reports), err)
+		}
+		return articles, reports[0]
+	}
+
+	if articles, report := scrape(); len(articles) != 2 || report.Unchanged != 0 {
+		t.Fatalf("first run = %d articles, %d unchanged pages", len(articles), report.Unchanged)
+	}
+
+	// 下载页面的这一轮还没有入库，未变化的页面照常解析
+	if articles, report := scrape(); len(articles) != 2 || report.Unchanged != 0 {
+		t.Errorf("run before the page was stored = %d articles, %d unchanged pages", len(articles), report.Unchanged)
+	}
+
+	// 页面下载之后的一轮已经入库，未变化的页面不再解析
+	s.SetLastRun("Example", time.Now())
+	articles, report := scrape()
+	if len(articles) != 0 || report.Unchanged != 1 || report.ItemsFound != 0 || report.Failed() {
+		t.Errorf("run after the page was stored = %d articles, report %+v", len(articles), report)
+	}
+	if _, degraded := DetectDrift(report, []SourceReport{{ItemsParsed: 2}, {ItemsParsed: 2}, {ItemsParsed: 2}}); degraded {
+		t.Error("an unchanged listing was reported as drift")
+	}
+
+	// 页面更新后重新解析
+	site.set(`"v2"`, cachedListingPage)
+	if articles, report := scrape(); len(articles) != 2 || report.Unchanged != 0 {
+		t.Errorf("run after the page changed = %d articles, %d unchanged pages", len(articles), report.Unchanged)
+	}
+}
@@ -0,0 +1,124 @@
+package scraper
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// srcsetAttrs 保存响应式图片候选列表的属性，按优先级排列
+var srcsetAttrs = []string{"data-srcset", "data-lazy-srcset", "srcset"}
+
+// lazyImageAttrs 懒加载图片保存真实地址的属性，按优先级排列
+var lazyImageAttrs = []string{"data-src", "data-lazy-src", "data-original", "data-lazy", "data-url", "data-hi-res-src"}
+
+// placeholderPattern 匹配懒加载占位图的常见文件名
+var placeholderPattern = regexp.MustCompile(`(?i)(placeholder|blank|spacer|transparent|pixel|lazy|loading|1x1)\.(gif|png|jpe?g|svg|webp)`)
+
+// backgroundPattern 匹配内联样式中的背景图片
+var backgroundPattern = regexp.MustCompile(`background(?:-image)?\s*:[^;]*url\(\s*['"]?([^'")]+)['"]?\s*\)`)
+
+// imageSource 返回元素对应图片的真实地址
+//
+// 依次尝试 srcset 中最大的候选、懒加载属性、<picture> 中的 <source> 以及 src，
+// 跳过 data: URI 和占位图。选择器匹配到的不是 <img> 时使用其中的第一张图片，
+// 最后尝试内联样式中的背景图片。
+func imageSource(sel *goquery.Selection) string {
+	if sel.Length() == 0 {
+		return ""
+	}
+
+	img := sel.First()
+	if !img.Is("img") {
+		if inner := img.Find("img").First(); inner.Length() > 0 {
+			img = inner
+		}
+	}
+
+	for _, attr := range srcsetAttrs {
+		if src := largestSrcset(img.AttrOr(attr, "")); usableImage(src) {
+			return src
+		}
+	}
+
+	for _, attr := range lazyImageAttrs {
+		if src := strings.TrimSpace(img.AttrOr(attr, "")); usableImage(src) {
+			return src
+		}
+	}
+
+	var fromPicture string
+	img.ParentsFiltered("picture").First().Find("source").EachWithBreak(func(_ int, source *goquery.Selection) bool {
+		for _, attr := range srcsetAttrs {
+			if src := largestSrcset(source.AttrOr(attr, "")); usableImage(src) {
+				fromPicture = src
+				return false
+			}
+		}
+		return true
+	})
+	if fromPicture != "" {
+		return fromPicture
+	}
+
+	if src := strings.TrimSpace(img.AttrOr("src", "")); usableImage(src) {
+		return src
+	}
+
+	if match := backgroundPattern.FindStringSubmatch(sel.First().AttrOr("style", "")); match != nil && usableImage(match[1]) {
+		return strings.TrimSpace(match[1])
+	}
+	return ""
+}
+
+// usableImage 判断地址是否是真实图片而不是占位图
+func usableImage(src string) bool {
+	return src != "" && !strings.HasPrefix(src, "data:") && !placeholderPattern.MatchString(src)
+}
+
+// descriptorPattern 匹配 srcset 中的宽度或像素密度描述符，以及紧跟在逗号后的下一个地址
+var descriptorPattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)[wx](?:,(.*))?$`)
+
+// largestSrcset 从 srcset 中选出宽度或像素密度最大的候选地址
+//
+// 按空白切分而不是按逗号切分，因为不少图床的地址本身含有逗号（如 w_300,h_200）。
+func largestSrcset(srcset string) string {
+	var best string
+	bestSize := -1.0
+
+	var current string
+	consider := func(src string, size float64) {
+		if src != "" && size > bestSize {
+			best = src
+			bestSize = size
+		}
+	}
+
+	for _, token := range strings.Fields(srcset) {
+		if current == "" {
+			// 没有描述符的候选相当于 1x
+			if strings.HasSuffix(token, ",") {
+				consider(strings.TrimSuffix(token, ","), 1)
+				continue
+			}
+			current = token
+			continue
+		}
+
+		match := descriptorPattern.FindStringSubmatch(token)
+		if match == nil {
+			consider(current, 1)
+			current = token
+			continue
+		}
+
+		size, _ := strconv.ParseFloat(match[1], 64)
+		consider(current, size)
+		current = match[2]
+	}
+	consider(current, 1)
+
+	return best
+}
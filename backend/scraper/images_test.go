@@ -0,0 +1,66 @@
+package scraper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestLargestSrcset(t *testing.T) {
+	tests := []struct {
+		name   string
+		srcset string
+		want   string
+	}{
+		{"empty", "", ""},
+		{"single url", "https://img.example.com/a.jpg", "https://img.example.com/a.jpg"},
+		{"widths", "a-320.jpg 320w, a-1280.jpg 1280w, a-640.jpg 640w", "a-1280.jpg"},
+		{"densities", "a.jpg 1x, a@2x.jpg 2x, a@1.5x.jpg 1.5x", "a@2x.jpg"},
+		{"no space after comma", "a-320.jpg 320w,a-960.jpg 960w", "a-960.jpg"},
+		{"url without descriptor counts as 1x", "a.jpg, a@2x.jpg 2x", "a@2x.jpg"},
+		{"only urls without descriptors", "a.jpg, b.jpg", "a.jpg"},
+		{"commas inside urls", "https://cdn.example.com/w_300,h_200/a.jpg 300w, https://cdn.example.com/w_900,h_600/a.jpg 900w",
+			"https://cdn.example.com/w_900,h_600/a.jpg"},
+		{"extra whitespace", "\n  a-320.jpg   320w,\n  a-1024.jpg  1024w\n", "a-1024.jpg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := largestSrcset(tt.srcset); got != tt.want {
+				t.Errorf("largestSrcset(%q) = %q, want %q", tt.srcset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageSource(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"src", `<img src="/a.jpg">`, "/a.jpg"},
+		{"srcset preferred over src", `<img src="/a-320.jpg" srcset="/a-320.jpg 320w, /a-1280.jpg 1280w">`, "/a-1280.jpg"},
+		{"lazy srcset preferred", `<img srcset="/small.jpg 320w" data-srcset="/big.jpg 1600w">`, "/big.jpg"},
+		{"lazy src over placeholder", `<img src="/img/placeholder.gif" data-src="/a.jpg">`, "/a.jpg"},
+		{"data uri skipped", `<img src="data:image/gif;base64,R0lGODlhAQABAAAAACw=" data-original="/a.jpg">`, "/a.jpg"},
+		{"picture source", `<picture><source srcset="/a.webp 1x, /a@2x.webp 2x"><img src="/img/blank.png"></picture>`, "/a@2x.webp"},
+		{"img inside container", `<div class="thumb"><a href="/x"><img src="/a.jpg"></a></div>`, "/a.jpg"},
+		{"background image", `<div class="thumb" style="background-image: url('/bg.jpg'); height: 90px"></div>`, "/bg.jpg"},
+		{"only placeholder", `<img src="/static/1x1.png">`, ""},
+		{"no image", `<span>text</span>`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader("<html><body>" + tt.html + "</body></html>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := imageSource(doc.Find("body").Children().First()); got != tt.want {
+				t.Errorf("imageSource(%s) = %q, want %q", tt.html, got, tt.want)
+			}
+		})
+	}
+}
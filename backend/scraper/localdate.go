@@ -0,0 +1,129 @@
+package scraper
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// localDateLayouts 是网页中常见的不带时区的日期格式，按来源的时区解析
+//
+// 月、日、时使用不补零的写法，解析时一位和两位数字都能匹配。
+var localDateLayouts = []string{
+	"2006-1-2T15:04:05",
+	"2006-1-2 15:04:05",
+	"2006-1-2 15:04",
+	"2006-1-2",
+	"2006/1/2 15:04:05",
+	"2006/1/2 15:04",
+	"2006/1/2",
+	"2006.1.2 15:04",
+	"2006.1.2",
+	"2006年1月2日 15:04:05",
+	"2006年1月2日 15:04",
+	"2006年1月2日15:04",
+	"2006年1月2日",
+}
+
+// yearlessLayouts 是省略了年份的日期格式，年份取当前年
+var yearlessLayouts = []string{
+	"1-2 15:04",
+	"1月2日 15:04",
+	"1月2日",
+}
+
+// relativeDatePattern 匹配“5分钟前”“3小时前”“2天前”这样的相对时间
+var relativeDatePattern = regexp.MustCompile(`^(\d+)\s*(秒|分钟|小时|天)前$`)
+
+// dayOffsetPattern 匹配“今天 12:30”“昨天 08:00”这样的写法，时间可以省略
+var dayOffsetPattern = regexp.MustCompile(`^(今天|昨天|前天)\s*(\d{1,2}:\d{2})?$`)
+
+// embeddedDatePattern 从“发布时间：2024-05-01 12:30 来源：xx”这样的文本中找出日期
+var embeddedDatePattern = regexp.MustCompile(`\d{4}\s*[-/.年]\s*\d{1,2}\s*[-/.月]\s*\d{1,2}\s*日?(?:\s*\d{1,2}:\d{2}(?::\d{2})?)?`)
+
+// parseLocalDate 解析网页中的发布时间，不带时区的日期按 loc 解析，loc 为 nil 时使用 UTC
+//
+// 除 parseDate 支持的格式外，还支持中文站点常见的“2024年5月1日 12:30”、省略年份的
+// “05-01 12:30”、“3小时前”“昨天 12:30”这样的相对时间，以及夹杂在其他文字中的日期。
+func parseLocalDate(value string, loc *time.Location, now time.Time) (time.Time, bool) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	value = strings.TrimSpace(value)
+
+	if t, ok := parseLocalLayouts(value, loc, now); ok {
+		return t, true
+	}
+	if t, ok := parseDate(value, loc); ok {
+		return t, true
+	}
+	if match := embeddedDatePattern.FindString(value); match != "" && match != value {
+		return parseLocalLayouts(match, loc, now)
+	}
+	return time.Time{}, false
+}
+
+// parseLocalLayouts 按不带时区的格式和相对时间解析整个字符串
+func parseLocalLayouts(value string, loc *time.Location, now time.Time) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	now = now.In(loc)
+
+	for _, layout := range localDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, true
+		}
+	}
+
+	for _, layout := range yearlessLayouts {
+		parsed, err := time.ParseInLocation(layout, value, loc)
+		if err != nil {
+			continue
+		}
+		t, ok := inYear(parsed, now.Year(), loc)
+		// 年初看到的去年12月的文章
+		if ok && t.After(now.AddDate(0, 0, 1)) {
+			t, ok = inYear(parsed, now.Year()-1, loc)
+		}
+		return t, ok
+	}
+
+	if value == "刚刚" {
+		return now, true
+	}
+
+	if match := relativeDatePattern.FindStringSubmatch(value); match != nil {
+		n, _ := strconv.Atoi(match[1])
+		unit := map[string]time.Duration{
+			"秒":  time.Second,
+			"分钟": time.Minute,
+			"小时": time.Hour,
+			"天":  24 * time.Hour,
+		}[match[2]]
+		return now.Add(-time.Duration(n) * unit), true
+	}
+
+	if match := dayOffsetPattern.FindStringSubmatch(value); match != nil {
+		days := map[string]int{"今天": 0, "昨天": 1, "前天": 2}[match[1]]
+		day := now.AddDate(0, 0, -days)
+		hour, minute := 0, 0
+		if match[2] != "" {
+			clock, _ := time.Parse("15:04", match[2])
+			hour, minute = clock.Hour(), clock.Minute()
+		}
+		return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, loc), true
+	}
+
+	return time.Time{}, false
+}
+
+// inYear 把省略了年份的日期放到 year 年，这一年没有这个日期（如平年的2月29日）时返回 false
+func inYear(t time.Time, year int, loc *time.Location) (time.Time, bool) {
+	date := time.Date(year, t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc)
+	if date.Month() != t.Month() || date.Day() != t.Day() {
+		return time.Time{}, false
+	}
+	return date, true
+}
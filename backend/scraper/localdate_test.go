@@ -0,0 +1,88 @@
+package scraper
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLocalDate(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+	at := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, shanghai)
+	}
+	newYear := at(2025, time.January, 1, 9, 30)
+	midYear := at(2024, time.June, 15, 10, 0)
+
+	tests := []struct {
+		name  string
+		value string
+		now   time.Time
+		want  time.Time
+		ok    bool
+	}{
+		{"full date and time", "2024-05-01 12:30", midYear, at(2024, time.May, 1, 12, 30), true},
+		{"unpadded date", "2024-5-1", midYear, at(2024, time.May, 1, 0, 0), true},
+		{"slashes", "2024/05/01 12:30:00", midYear, at(2024, time.May, 1, 12, 30), true},
+		{"chinese date", "2024年5月1日 12:30", midYear, at(2024, time.May, 1, 12, 30), true},
+		{"chinese date without space", "2024年5月1日12:30", midYear, at(2024, time.May, 1, 12, 30), true},
+		{"embedded date", "发布时间：2024-05-01 12:30 来源：游戏网", midYear, at(2024, time.May, 1, 12, 30), true},
+		{"zoned date ignores loc", "2024-05-01T12:30:00Z", midYear, time.Date(2024, time.May, 1, 12, 30, 0, 0, time.UTC), true},
+
+		{"yearless this year", "06-14 08:00", midYear, at(2024, time.June, 14, 8, 0), true},
+		{"yearless chinese", "6月14日", midYear, at(2024, time.June, 14, 0, 0), true},
+		{"yearless december seen on new year's day", "12-31 23:50", newYear, at(2024, time.December, 31, 23, 50), true},
+		{"yearless chinese december seen on new year's day", "12月30日 18:00", newYear, at(2024, time.December, 30, 18, 0), true},
+		{"yearless new year's day", "01-01 08:00", newYear, at(2025, time.January, 1, 8, 0), true},
+		{"yearless tomorrow stays this year", "1月2日", newYear, at(2025, time.January, 2, 0, 0), true},
+		{"yearless january seen on new year's eve", "1月1日", at(2024, time.December, 31, 23, 0), at(2024, time.January, 1, 0, 0), true},
+		{"yearless leap day in a leap year", "2月29日", at(2024, time.March, 1, 9, 0), at(2024, time.February, 29, 0, 0), true},
+		{"yearless leap day in a common year", "02-29 08:00", at(2025, time.March, 5, 9, 0), time.Time{}, false},
+		{"yearless leap day seen early in a common year", "2月29日", newYear, time.Time{}, false},
+
+		{"just now", "刚刚", newYear, newYear, true},
+		{"minutes ago", "5分钟前", newYear, newYear.Add(-5 * time.Minute), true},
+		{"hours ago", "3小时前", newYear, newYear.Add(-3 * time.Hour), true},
+		{"hours ago across new year", "10小时前", newYear, at(2024, time.December, 31, 23, 30), true},
+		{"days ago", "2天前", newYear, at(2024, time.December, 30, 9, 30), true},
+		{"today", "今天 08:15", newYear, at(2025, time.January, 1, 8, 15), true},
+		{"yesterday across new year", "昨天 22:00", newYear, at(2024, time.December, 31, 22, 0), true},
+		{"yesterday without time", "昨天", newYear, at(2024, time.December, 31, 0, 0), true},
+		{"day before yesterday", "前天 7:05", newYear, at(2024, time.December, 30, 7, 5), true},
+
+		{"empty", "", newYear, time.Time{}, false},
+		{"not a date", "未知", newYear, time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseLocalDate(tt.value, shanghai, tt.now)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("parseLocalDate(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseLocalDateNow(t *testing.T) {
+	// now 可以是任意时区，相对时间按来源的时区计算日期
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+	now := time.Date(2024, time.December, 31, 17, 0, 0, 0, time.UTC) // 北京时间 2025-01-01 01:00
+
+	got, ok := parseLocalDate("昨天 12:00", shanghai, now)
+	want := time.Date(2024, time.December, 31, 12, 0, 0, 0, shanghai)
+	if !ok || !got.Equal(want) {
+		t.Errorf("parseLocalDate() = %v, %v, want %v", got, ok, want)
+	}
+
+	got, ok = parseLocalDate("2024-05-01", nil, now)
+	want = time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC)
+	if !ok || !got.Equal(want) {
+		t.Errorf("parseLocalDate() with nil loc = %v, %v, want %v", got, ok, want)
+	}
+}
@@ -0,0 +1,231 @@
+package scraper
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Metadata 是从详情页结构化数据中提取的文章元信息
+//
+// 依次读取 JSON-LD (NewsArticle 等)、Open Graph / article:* 以及普通 meta 标签，
+// 先读到的值优先。
+type Metadata struct {
+	Title        string
+	Description  string
+	Author       string
+	CanonicalURL string
+	ImageURL     string
+	PublishedAt  time.Time
+	ModifiedAt   time.Time
+}
+
+// articleTypes 是被视为文章的 JSON-LD @type
+var articleTypes = map[string]bool{
+	"NewsArticle":         true,
+	"Article":             true,
+	"BlogPosting":         true,
+	"ReportageNews":       true,
+	"AnalysisNewsArticle": true,
+	"ReviewNewsArticle":   true,
+	"Review":              true,
+}
+
+// extractMetadata 从详情页文档中提取元信息，pageURL 用于补全相对地址
+func extractMetadata(doc *goquery.Selection, pageURL string) Metadata {
+	var m Metadata
+
+	// JSON-LD
+	doc.Find(`script[type="application/ld+json"]`).Each(func(_ int, script *goquery.Selection) {
+		var data interface{}
+		if err := json.Unmarshal([]byte(script.Text()), &data); err != nil {
+			return
+		}
+		for _, obj := range findArticleObjects(data) {
+			m.mergeJSONLD(obj)
+		}
+	})
+
+	// Open Graph 与 article:* 标签
+	m.fill(&m.Title, metaContent(doc, "og:title"))
+	m.fill(&m.Description, metaContent(doc, "og:description"))
+	m.fill(&m.ImageURL, metaContent(doc, "og:image", "og:image:url", "og:image:secure_url", "twitter:image"))
+	m.fill(&m.CanonicalURL, linkHref(doc, "canonical"), metaContent(doc, "og:url"))
+	m.fill(&m.Author, metaContent(doc, "article:author", "author", "parsely-author", "dc.creator"))
+	m.fillTime(&m.PublishedAt, metaContent(doc, "article:published_time", "og:published_time", "pubdate", "publish-date", "date", "dc.date"))
+	m.fillTime(&m.ModifiedAt, metaContent(doc, "article:modified_time", "og:updated_time", "lastmod"))
+
+	// 普通 meta 标签与 <time> 元素
+	m.fill(&m.Description, metaContent(doc, "description", "twitter:description"))
+	m.fill(&m.Title, metaContent(doc, "twitter:title"), strings.TrimSpace(doc.Find("title").First().Text()))
+	m.fill(&m.ImageURL, linkHref(doc, "image_src"))
+	if datetime, ok := doc.Find("time[datetime]").First().Attr("datetime"); ok {
+		m.fillTime(&m.PublishedAt, datetime)
+	}
+
+	m.ImageURL = resolveURL(pageURL, m.ImageURL)
+	m.CanonicalURL = resolveURL(pageURL, m.CanonicalURL)
+
+	// article:author 常常是作者主页链接，此时不作为作者名
+	if strings.HasPrefix(m.Author, "http") {
+		m.Author = ""
+	}
+
+	return m
+}
+
+// findArticleObjects 在 JSON-LD 数据（可能是数组或 @graph）中查找文章对象
+func findArticleObjects(data interface{}) []map[string]interface{} {
+	var found []map[string]interface{}
+
+	switch v := data.(type) {
+	case []interface{}:
+		for _, item := range v {
+			found = append(found, findArticleObjects(item)...)
+		}
+	case map[string]interface{}:
+		if graph, ok := v["@graph"]; ok {
+			found = append(found, findArticleObjects(graph)...)
+		}
+		for _, t := range jsonStrings(v["@type"]) {
+			if articleTypes[t] {
+				found = append(found, v)
+				break
+			}
+		}
+	}
+
+	return found
+}
+
+// mergeJSONLD 将 JSON-LD 文章对象中的字段合并到元信息
+func (m *Metadata) mergeJSONLD(obj map[string]interface{}) {
+	m.fill(&m.Title, jsonFirst(obj["headline"]), jsonFirst(obj["name"]))
+	m.fill(&m.Description, jsonFirst(obj["description"]))
+	m.fill(&m.Author, jsonName(obj["author"]))
+	m.fill(&m.ImageURL, jsonURL(obj["image"]), jsonURL(obj["thumbnailUrl"]))
+	m.fill(&m.CanonicalURL, jsonURL(obj["mainEntityOfPage"]), jsonFirst(obj["url"]))
+	m.fillTime(&m.PublishedAt, jsonFirst(obj["datePublished"]), jsonFirst(obj["dateCreated"]))
+	m.fillTime(&m.ModifiedAt, jsonFirst(obj["dateModified"]))
+}
+
+// fill 在字段为空时填入第一个非空值
+func (m *Metadata) fill(field *string, values ...string) {
+	if *field != "" {
+		return
+	}
+	*field = strings.TrimSpace(firstNonEmpty(values...))
+}
+
+// fillTime 在时间字段为空时填入第一个能解析的值
+func (m *Metadata) fillTime(field *time.Time, values ...string) {
+	if !field.IsZero() {
+		return
+	}
+	for _, value := range values {
+		if t, ok := parseDate(value, time.UTC); ok {
+			*field = t
+			return
+		}
+	}
+}
+
+// metaContent 按 property 或 name 查找 meta 标签的 content
+func metaContent(doc *goquery.Selection, keys ...string) string {
+	for _, key := range keys {
+		for _, attr := range []string{"property", "name", "itemprop"} {
+			sel := doc.Find("meta[" + attr + "=\"" + key + "\"]").First()
+			if content, ok := sel.Attr("content"); ok && strings.TrimSpace(content) != "" {
+				return strings.TrimSpace(content)
+			}
+		}
+	}
+	return ""
+}
+
+// linkHref 返回指定 rel 的 link 标签地址
+func linkHref(doc *goquery.Selection, rel string) string {
+	href, _ := doc.Find("link[rel=\"" + rel + "\"]").First().Attr("href")
+	return strings.TrimSpace(href)
+}
+
+// jsonStrings 将 JSON-LD 中的字符串或字符串数组统一为切片
+func jsonStrings(v interface{}) []string {
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		values := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// jsonFirst 返回 JSON-LD 字段中的第一个字符串
+func jsonFirst(v interface{}) string {
+	if values := jsonStrings(v); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// jsonName 读取作者字段，可能是字符串、Person 对象或它们的数组
+func jsonName(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case map[string]interface{}:
+		return jsonFirst(t["name"])
+	case []interface{}:
+		names := make([]string, 0, len(t))
+		for _, item := range t {
+			if name := jsonName(item); name != "" {
+				names = append(names, name)
+			}
+		}
+		return strings.Join(names, ", ")
+	}
+	return ""
+}
+
+// jsonURL 读取图片或页面字段，可能是字符串、ImageObject/WebPage 对象或数组
+func jsonURL(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case map[string]interface{}:
+		return firstNonEmpty(jsonFirst(t["url"]), jsonFirst(t["@id"]), jsonFirst(t["contentUrl"]))
+	case []interface{}:
+		for _, item := range t {
+			if u := jsonURL(item); u != "" {
+				return u
+			}
+		}
+	}
+	return ""
+}
+
+// resolveURL 将相对地址转换为基于 pageURL 的绝对地址
+func resolveURL(pageURL, ref string) string {
+	if ref == "" || strings.HasPrefix(ref, "http") {
+		return ref
+	}
+
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return ref
+	}
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
@@ -0,0 +1,144 @@
+package scraper
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const metadataPageURL = "https://www.example.com/news/zelda"
+
+func TestExtractMetadata(t *testing.T) {
+	published := time.Date(2024, time.March, 5, 10, 30, 0, 0, time.UTC)
+	modified := time.Date(2024, time.March, 6, 8, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		head string
+		want Metadata
+	}{
+		{
+			name: "json-ld news article",
+			head: `<script type="application/ld+json">{
+				"@context": "https://schema.org",
+				"@type": "NewsArticle",
+				"headline": "Zelda sequel announced",
+				"description": "Nintendo confirmed it.",
+				"author": [{"@type": "Person", "name": "Jane Doe"}, {"@type": "Person", "name": "John Roe"}],
+				"image": {"@type": "ImageObject", "url": "/images/zelda.jpg"},
+				"mainEntityOfPage": {"@type": "WebPage", "@id": "https://www.example.com/news/zelda"},
+				"datePublished": "2024-03-05T10:30:00Z",
+				"dateModified": "2024-03-06T08:00:00Z"
+			}</script>`,
+			want: Metadata{
+				Title:        "Zelda sequel announced",
+				Description:  "Nintendo confirmed it.",
+				Author:       "Jane Doe, John Roe",
+				CanonicalURL: "https://www.example.com/news/zelda",
+				ImageURL:     "https://www.example.com/images/zelda.jpg",
+				PublishedAt:  published,
+				ModifiedAt:   modified,
+			},
+		},
+		{
+			name: "json-ld graph with several types",
+			head: `<script type="application/ld+json">{"@graph": [
+				{"@type": "WebSite", "name": "Example"},
+				{"@type": ["Article", "ReviewNewsArticle"], "name": "Zelda review", "author": "Jane Doe",
+				 "image": ["https://cdn.example.com/a.jpg", "https://cdn.example.com/b.jpg"], "datePublished": "2024-03-05T10:30:00Z"}
+			]}</script>`,
+			want: Metadata{
+				Title:       "Zelda review",
+				Author:      "Jane Doe",
+				ImageURL:    "https://cdn.example.com/a.jpg",
+				PublishedAt: published,
+			},
+		},
+		{
+			name: "open graph",
+			head: `<meta property="og:title" content=" Zelda sequel announced ">
+				<meta property="og:description" content="Nintendo confirmed it.">
+				<meta property="og:image" content="https://cdn.example.com/og.jpg">
+				<meta property="og:url" content="https://www.example.com/news/zelda">
+				<meta property="article:author" content="https://www.example.com/authors/jane">
+				<meta property="article:published_time" content="2024-03-05T10:30:00Z">
+				<meta property="article:modified_time" content="2024-03-06T08:00:00Z">`,
+			want: Metadata{
+				Title:        "Zelda sequel announced",
+				Description:  "Nintendo confirmed it.",
+				CanonicalURL: "https://www.example.com/news/zelda",
+				ImageURL:     "https://cdn.example.com/og.jpg",
+				PublishedAt:  published,
+				ModifiedAt:   modified,
+			},
+		},
+		{
+			name: "json-ld preferred over open graph",
+			head: `<script type="application/ld+json">{"@type": "NewsArticle", "headline": "From JSON-LD"}</script>
+				<meta property="og:title" content="From Open Graph">
+				<meta property="og:description" content="Description from Open Graph">`,
+			want: Metadata{
+				Title:       "From JSON-LD",
+				Description: "Description from Open Graph",
+			},
+		},
+		{
+			name: "plain meta tags",
+			head: `<title>Zelda sequel announced | Example</title>
+				<meta name="description" content="Nintendo confirmed it.">
+				<meta name="author" content="Jane Doe">
+				<link rel="canonical" href="/news/zelda?ref=rss">
+				<link rel="image_src" href="/images/zelda.jpg">`,
+			want: Metadata{
+				Title:        "Zelda sequel announced | Example",
+				Description:  "Nintendo confirmed it.",
+				Author:       "Jane Doe",
+				CanonicalURL: "https://www.example.com/news/zelda?ref=rss",
+				ImageURL:     "https://www.example.com/images/zelda.jpg",
+			},
+		},
+		{
+			name: "invalid json-ld ignored",
+			head: `<script type="application/ld+json">{"@type": "NewsArticle", "headline": </script>
+				<meta property="og:title" content="From Open Graph">`,
+			want: Metadata{Title: "From Open Graph"},
+		},
+		{
+			name: "empty page",
+			want: Metadata{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader("<html><head>" + tt.head + "</head><body></body></html>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := extractMetadata(doc.Selection, metadataPageURL)
+
+			if !got.PublishedAt.Equal(tt.want.PublishedAt) || !got.ModifiedAt.Equal(tt.want.ModifiedAt) {
+				t.Errorf("dates = %v, %v, want %v, %v", got.PublishedAt, got.ModifiedAt, tt.want.PublishedAt, tt.want.ModifiedAt)
+			}
+			got.PublishedAt, got.ModifiedAt = tt.want.PublishedAt, tt.want.ModifiedAt
+			if got != tt.want {
+				t.Errorf("extractMetadata() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractMetadataTimeElement(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(
+		`<html><body><article><time datetime="2024-03-05T18:30:00+08:00">3月5日</time></article></body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := extractMetadata(doc.Selection, metadataPageURL)
+	if want := time.Date(2024, time.March, 5, 10, 30, 0, 0, time.UTC); !got.PublishedAt.Equal(want) {
+		t.Errorf("PublishedAt = %v, want %v", got.PublishedAt, want)
+	}
+}
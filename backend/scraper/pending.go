@@ -0,0 +1,74 @@
+package scraper
+
+import "log"
+
+// maxPendingAttempts 是一篇文章详情页抓取失败后最多重新提交的次数，按每小时抓取一次约为一天
+const maxPendingAttempts = 24
+
+// pendingArticle 是详情页抓取失败、等待下次抓取时重新提交的文章
+type pendingArticle struct {
+	article  Article
+	attempts int
+}
+
+// RetryLater 记录一篇详情页抓取失败的文章，之后每次抓取它的来源时都会和列表页的文章
+// 一起重新提交，直到调用 Stored 或失败次数达到上限
+//
+// sitemap 等增量来源只提交上次运行以来更新的条目，抓取失败的条目下次不会再出现在
+// 结果中；没有标题的 sitemap 条目在详情页失败时也无法入库。记录在内存中，服务重启后丢失。
+func (s *Scraper) RetryLater(article Article) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	bySource := s.pending[article.Source]
+	if bySource == nil {
+		bySource = make(map[string]*pendingArticle)
+		s.pending[article.Source] = bySource
+	}
+
+	entry, ok := bySource[article.ID]
+	if !ok {
+		entry = &pendingArticle{}
+		bySource[article.ID] = entry
+	}
+	entry.article = article
+	entry.attempts++
+
+	if entry.attempts >= maxPendingAttempts {
+		log.Printf("Giving up on %s after %d failed detail fetches", article.URL, entry.attempts)
+		delete(bySource, article.ID)
+	}
+}
+
+// Stored 表示这些文章已经成功入库，不再重新提交
+func (s *Scraper) Stored(ids ...string) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	for _, bySource := range s.pending {
+		for _, id := range ids {
+			delete(bySource, id)
+		}
+	}
+}
+
+// withPending 将来源等待重新提交的文章加入本次抓取的结果，已在结果中的不重复加入
+func (s *Scraper) withPending(source string, articles []Article) []Article {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	if len(s.pending[source]) == 0 {
+		return articles
+	}
+
+	listed := make(map[string]bool, len(articles))
+	for _, article := range articles {
+		listed[article.ID] = true
+	}
+	for id, entry := range s.pending[source] {
+		if !listed[id] {
+			articles = append(articles, entry.article)
+		}
+	}
+	return articles
+}
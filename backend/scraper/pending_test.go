@@ -0,0 +1,61 @@
+package scraper
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func pendingIDs(articles []Article) []string {
+	ids := make([]string, len(articles))
+	for i, article := range articles {
+		ids[i] = article.ID
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestPendingArticles(t *testing.T) {
+	s := NewScraperWithConfig(&Config{})
+
+	failed := Article{ID: "failed", Source: "Sitemap"}
+	other := Article{ID: "other", Source: "Feed"}
+	s.RetryLater(failed)
+	s.RetryLater(other)
+
+	listed := []Article{{ID: "new", Source: "Sitemap"}}
+	if got := pendingIDs(s.withPending("Sitemap", listed)); !reflect.DeepEqual(got, []string{"failed", "new"}) {
+		t.Errorf("withPending() = %q, want the listed and the failed article", got)
+	}
+
+	// 再次出现在列表中的文章不重复提交
+	listed = []Article{{ID: "failed", Source: "Sitemap", Title: "listed again"}}
+	if got := s.withPending("Sitemap", listed); len(got) != 1 || got[0].Title != "listed again" {
+		t.Errorf("withPending() = %+v, want only the listed article", got)
+	}
+
+	s.Stored("failed")
+	if got := s.withPending("Sitemap", nil); len(got) != 0 {
+		t.Errorf("withPending() after Stored = %+v, want none", got)
+	}
+	if got := pendingIDs(s.withPending("Feed", nil)); !reflect.DeepEqual(got, []string{"other"}) {
+		t.Errorf("withPending() of another source = %q, want [other]", got)
+	}
+}
+
+func TestPendingArticlesGiveUp(t *testing.T) {
+	s := NewScraperWithConfig(&Config{})
+	article := Article{ID: "broken", Source: "Sitemap"}
+
+	for i := 1; i < maxPendingAttempts; i++ {
+		s.RetryLater(article)
+	}
+	if got := s.withPending("Sitemap", nil); len(got) != 1 {
+		t.Fatalf("withPending() after %d failures = %+v, want the article", maxPendingAttempts-1, got)
+	}
+
+	s.RetryLater(article)
+	if got := s.withPending("Sitemap", nil); len(got) != 0 {
+		t.Errorf("withPending() after %d failures = %+v, want none", maxPendingAttempts, got)
+	}
+}
@@ -0,0 +1,297 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math/rand"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/temoto/robotstxt"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultUserAgent 是爬虫默认使用的标识，便于站点识别并联系我们
+const DefaultUserAgent = "GameNewsBot/1.0 (+https://github.com/phuhao00/game_news)"
+
+// robotsTTL 是 robots.txt 的缓存时间
+const robotsTTL = 24 * time.Hour
+
+// ErrDisallowedByRobots 表示请求的URL被 robots.txt 禁止抓取
+var ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+
+// PolitenessConfig 描述访问各个域名时的并发与间隔限制
+type PolitenessConfig struct {
+	// Default 用于没有单独配置的域名
+	Default DomainPolicy `yaml:"default" json:"default"`
+
+	// Domains 按顺序匹配的域名策略，先匹配到的生效
+	Domains []DomainPolicy `yaml:"domains" json:"domains"`
+}
+
+// DomainPolicy 是单个域名的访问策略
+type DomainPolicy struct {
+	// Domain 域名或通配符，例如 "*.ign.com"
+	Domain      string   `yaml:"domain" json:"domain"`
+	Parallelism int      `yaml:"parallelism" json:"parallelism"`
+	Delay       Duration `yaml:"delay" json:"delay"`
+	RandomDelay Duration `yaml:"random_delay" json:"random_delay"`
+}
+
+// Duration 在配置中写成 "1s"、"500ms" 这样的字符串
+type Duration time.Duration
+
+// UnmarshalYAML 解析时长字符串
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	parsed, err := time.ParseDuration(value.Value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// UnmarshalJSON 解析时长字符串
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// policyFor 返回域名对应的访问策略
+func (p PolitenessConfig) policyFor(host string) DomainPolicy {
+	policy := p.Default
+	for _, candidate := range p.Domains {
+		if matched, _ := path.Match(candidate.Domain, host); matched || candidate.Domain == host {
+			policy = candidate
+			break
+		}
+	}
+
+	if policy.Parallelism <= 0 {
+		policy.Parallelism = 2
+	}
+	return policy
+}
+
+// politeTransport 在同一个 Scraper 的所有 collector 之间共享，
+// 负责检查 robots.txt，并按域名限制并发数和请求间隔。
+type politeTransport struct {
+	base      http.RoundTripper
+	userAgent string
+	policy    PolitenessConfig
+	robots    *robotsCache
+
+	mu       sync.Mutex
+	limiters map[string]*hostLimiter
+}
+
+func newPoliteTransport(base http.RoundTripper, userAgent string, policy PolitenessConfig, respectRobots bool) *politeTransport {
+	t := &politeTransport{
+		base:      base,
+		userAgent: userAgent,
+		policy:    policy,
+		limiters:  make(map[string]*hostLimiter),
+	}
+	if respectRobots {
+		t.robots = newRobotsCache(base, userAgent)
+	}
+	return t
+}
+
+func (t *politeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := strings.ToLower(req.URL.Hostname())
+
+	var crawlDelay time.Duration
+	if t.robots != nil {
+		group, err := t.robots.group(req)
+		if err != nil {
+			return nil, err
+		}
+		if !group.Test(req.URL.RequestURI()) {
+			log.Printf("Skipping %s: %v", req.URL, ErrDisallowedByRobots)
+			return nil, ErrDisallowedByRobots
+		}
+		crawlDelay = group.CrawlDelay
+	}
+
+	limiter := t.limiter(host, crawlDelay)
+	if err := limiter.acquire(req); err != nil {
+		return nil, err
+	}
+	defer limiter.release()
+
+	// 域名限速放行之后才占用全局名额，见 budgetTransport
+	if err := admitBudget(req); err != nil {
+		return nil, err
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// limiter 返回域名的限速器，请求间隔取配置值与 robots.txt Crawl-delay 中较大的一个
+//
+// robots.txt 每 robotsTTL 重新获取一次，限速器在整个进程中复用，
+// 因此每次都按当前的 Crawl-delay 更新间隔。
+func (t *politeTransport) limiter(host string, crawlDelay time.Duration) *hostLimiter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	policy := t.policy.policyFor(host)
+	delay := time.Duration(policy.Delay)
+	if crawlDelay > delay {
+		delay = crawlDelay
+	}
+
+	if l, ok := t.limiters[host]; ok {
+		l.setDelay(delay)
+		return l
+	}
+
+	l := &hostLimiter{
+		slots:       make(chan struct{}, policy.Parallelism),
+		delay:       delay,
+		randomDelay: time.Duration(policy.RandomDelay),
+	}
+	t.limiters[host] = l
+	return l
+}
+
+// hostLimiter 限制单个域名的并发请求数与请求间隔
+type hostLimiter struct {
+	slots       chan struct{}
+	delay       time.Duration
+	randomDelay time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// acquire 占用一个并发名额，并等待到允许发出下一个请求的时间
+func (l *hostLimiter) acquire(req *http.Request) error {
+	select {
+	case l.slots <- struct{}{}:
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+
+	l.mu.Lock()
+	start := time.Now()
+	if l.next.After(start) {
+		start = l.next
+	}
+	interval := l.delay
+	if l.randomDelay > 0 {
+		interval += time.Duration(rand.Int63n(int64(l.randomDelay)))
+	}
+	l.next = start.Add(interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(start))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		l.release()
+		return req.Context().Err()
+	}
+}
+
+func (l *hostLimiter) release() {
+	<-l.slots
+}
+
+// setDelay 修改请求间隔，已经排定的下一个请求时间不受影响
+func (l *hostLimiter) setDelay(delay time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.delay = delay
+}
+
+// robotsCache 缓存各站点的 robots.txt
+type robotsCache struct {
+	client    *http.Client
+	userAgent string
+
+	mu      sync.Mutex
+	entries map[string]robotsEntry
+}
+
+type robotsEntry struct {
+	data      *robotstxt.RobotsData
+	fetchedAt time.Time
+}
+
+func newRobotsCache(base http.RoundTripper, userAgent string) *robotsCache {
+	return &robotsCache{
+		client:    &http.Client{Transport: base, Timeout: 10 * time.Second},
+		userAgent: userAgent,
+		entries:   make(map[string]robotsEntry),
+	}
+}
+
+// group 返回请求所在站点中适用于本爬虫的 robots.txt 规则组，
+// 只有请求的 context 在获取 robots.txt 期间被取消时返回错误
+func (r *robotsCache) group(req *http.Request) (*robotstxt.Group, error) {
+	key := req.URL.Scheme + "://" + req.URL.Host
+
+	r.mu.Lock()
+	entry, ok := r.entries[key]
+	r.mu.Unlock()
+
+	if !ok || time.Since(entry.fetchedAt) > robotsTTL {
+		data, err := r.fetch(req.Context(), key)
+		if err != nil {
+			return nil, err
+		}
+		entry = robotsEntry{data: data, fetchedAt: time.Now()}
+		r.mu.Lock()
+		r.entries[key] = entry
+		r.mu.Unlock()
+	}
+
+	return entry.data.FindGroup(r.userAgent), nil
+}
+
+// fetch 下载并解析 robots.txt，无法获取时视为允许抓取
+//
+// ctx 被取消时返回 ctx 的错误，不把中断当作站点没有 robots.txt 缓存下来。
+func (r *robotsCache) fetch(ctx context.Context, site string) (*robotstxt.RobotsData, error) {
+	allowAll, _ := robotstxt.FromStatusAndBytes(http.StatusNotFound, nil)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, site+"/robots.txt", nil)
+	if err != nil {
+		return allowAll, nil
+	}
+	req.Header.Set("User-Agent", r.userAgent)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		log.Printf("Failed to fetch %s/robots.txt: %v", site, err)
+		return allowAll, nil
+	}
+	defer resp.Body.Close()
+
+	data, err := robotstxt.FromResponse(resp)
+	if err != nil {
+		log.Printf("Failed to parse %s/robots.txt: %v", site, err)
+		return allowAll, nil
+	}
+	return data, nil
+}
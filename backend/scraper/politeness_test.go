@@ -0,0 +1,223 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// robotsSite 提供可以随时修改的 robots.txt，其他路径返回空白页面
+type robotsSite struct {
+	mu     sync.Mutex
+	robots string
+}
+
+func newRobotsSite(t *testing.T, robots string) (*robotsSite, *httptest.Server) {
+	site := &robotsSite{robots: robots}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			site.mu.Lock()
+			defer site.mu.Unlock()
+			w.Write([]byte(site.robots))
+			return
+		}
+		w.Write([]byte("<html><body></body></html>"))
+	}))
+	t.Cleanup(server.Close)
+	return site, server
+}
+
+func (s *robotsSite) setRobots(robots string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.robots = robots
+}
+
+func get(t *testing.T, transport http.RoundTripper, url string) error {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func TestPoliteTransportRobots(t *testing.T) {
+	_, server := newRobotsSite(t, "User-agent: *\nDisallow: /private\n\nUser-agent: OtherBot\nDisallow: /\n")
+	transport := newPoliteTransport(http.DefaultTransport, DefaultUserAgent, PolitenessConfig{}, true)
+
+	tests := []struct {
+		path string
+		want error
+	}{
+		{"/news", nil},
+		{"/private", ErrDisallowedByRobots},
+		{"/private/page", ErrDisallowedByRobots},
+		{"/privacy", nil},
+	}
+
+	for _, tt := range tests {
+		if err := get(t, transport, server.URL+tt.path); !errors.Is(err, tt.want) {
+			t.Errorf("GET %s error = %v, want %v", tt.path, err, tt.want)
+		}
+	}
+
+	// robots.txt 可以关闭
+	ignoring := newPoliteTransport(http.DefaultTransport, DefaultUserAgent, PolitenessConfig{}, false)
+	if err := get(t, ignoring, server.URL+"/private"); err != nil {
+		t.Errorf("GET /private with robots.txt ignored error = %v", err)
+	}
+}
+
+func TestRobotsDisallowReported(t *testing.T) {
+	_, server := newRobotsSite(t, "User-agent: *\nDisallow: /feed.xml\n")
+	s := NewScraperWithConfig(&Config{Sources: []SourceConfig{{
+		Name:    "Example",
+		Type:    "feed",
+		FeedURL: StringList{server.URL + "/feed.xml"},
+	}}})
+
+	_, reports, _ := s.ScrapeGamesWithReports()
+	if len(reports) != 1 {
+		t.Fatalf("got %d reports, want 1", len(reports))
+	}
+	report := reports[0]
+	if !report.Failed() || len(report.Errors) != 1 || !strings.Contains(report.Errors[0], ErrDisallowedByRobots.Error()) {
+		t.Errorf("report errors = %q, want the robots.txt disallow", report.Errors)
+	}
+
+	// 被 robots.txt 禁止不是站点故障，不计入熔断
+	for _, state := range s.BreakerStates() {
+		if state.ConsecutiveFailures != 0 {
+			t.Errorf("breaker of %s counted %d failures", state.Source, state.ConsecutiveFailures)
+		}
+	}
+}
+
+func TestPoliteTransportCrawlDelay(t *testing.T) {
+	site, server := newRobotsSite(t, "User-agent: *\nCrawl-delay: 0.2\n")
+	transport := newPoliteTransport(http.DefaultTransport, DefaultUserAgent,
+		PolitenessConfig{Default: DomainPolicy{Delay: Duration(50 * time.Millisecond)}}, true)
+	host := strings.TrimPrefix(server.URL, "http://")
+	host = host[:strings.LastIndex(host, ":")]
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := get(t, transport, server.URL+"/news"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("three requests took %v, want at least two crawl delays of 200ms", elapsed)
+	}
+
+	// robots.txt 过期后重新获取，新的 Crawl-delay 作用在已有的限速器上
+	site.setRobots("User-agent: *\nCrawl-delay: 1\n")
+	for key, entry := range transport.robots.entries {
+		entry.fetchedAt = time.Now().Add(-robotsTTL - time.Minute)
+		transport.robots.entries[key] = entry
+	}
+	if err := get(t, transport, server.URL+"/news"); err != nil {
+		t.Fatal(err)
+	}
+	if delay := transport.limiters[host].delay; delay != time.Second {
+		t.Errorf("delay after a longer Crawl-delay = %v, want 1s", delay)
+	}
+
+	// Crawl-delay 不会把间隔缩短到配置值以下
+	site.setRobots("User-agent: *\nCrawl-delay: 0.01\n")
+	for key, entry := range transport.robots.entries {
+		entry.fetchedAt = time.Now().Add(-robotsTTL - time.Minute)
+		transport.robots.entries[key] = entry
+	}
+	if err := get(t, transport, server.URL+"/news"); err != nil {
+		t.Fatal(err)
+	}
+	if delay := transport.limiters[host].delay; delay != 50*time.Millisecond {
+		t.Errorf("delay after a shorter Crawl-delay = %v, want the configured 50ms", delay)
+	}
+}
+
+func TestPoliteTransportParallelism(t *testing.T) {
+	var mu sync.Mutex
+	inFlight := make(map[string]int)
+	maxInFlight := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host[:strings.LastIndex(r.Host, ":")]
+		mu.Lock()
+		inFlight[host]++
+		if inFlight[host] > maxInFlight[host] {
+			maxInFlight[host] = inFlight[host]
+		}
+		mu.Unlock()
+
+		time.Sleep(50 * time.Millisecond)
+
+		mu.Lock()
+		inFlight[host]--
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	port := server.URL[strings.LastIndex(server.URL, ":"):]
+	transport := newPoliteTransport(http.DefaultTransport, DefaultUserAgent, PolitenessConfig{
+		Default: DomainPolicy{Parallelism: 2},
+		Domains: []DomainPolicy{{Domain: "local*", Parallelism: 3}},
+	}, false)
+
+	var wg sync.WaitGroup
+	for _, host := range []string{"127.0.0.1", "localhost"} {
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(url string) {
+				defer wg.Done()
+				if err := get(t, transport, url); err != nil {
+					t.Error(err)
+				}
+			}("http://" + host + port + "/news")
+		}
+	}
+	wg.Wait()
+
+	want := map[string]int{"127.0.0.1": 2, "localhost": 3}
+	for host, limit := range want {
+		if maxInFlight[host] != limit {
+			t.Errorf("%s had at most %d requests in flight, want %d", host, maxInFlight[host], limit)
+		}
+	}
+}
+
+func TestPoliteTransportCancelledRobots(t *testing.T) {
+	started := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	transport := newPoliteTransport(http.DefaultTransport, DefaultUserAgent, PolitenessConfig{}, true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/news", nil)
+	if _, err := transport.RoundTrip(req); !errors.Is(err, context.Canceled) {
+		t.Errorf("RoundTrip() error = %v, want context.Canceled", err)
+	}
+
+	// 中断的获取不当作站点没有 robots.txt 缓存下来
+	if len(transport.robots.entries) != 0 {
+		t.Errorf("cached %d robots.txt entries after a cancelled fetch", len(transport.robots.entries))
+	}
+}
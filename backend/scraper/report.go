@@ -0,0 +1,171 @@
+package scraper
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// SourceReport 是单个来源一次抓取的运行报告
+type SourceReport struct {
+	RunID       string
+	Source      string
+	StartedAt   time.Time
+	Duration    time.Duration
+	URLsVisited []string
+	Statuses    map[int]int
+	ItemsFound  int
+	ItemsParsed int
+	// FieldCounts 记录解析出的文章中各字段非空的数量，用于发现选择器失效
+	FieldCounts map[string]int
+	// Incremental 表示来源只提交上次运行以来的新文章，每次的文章数本就会有起伏
+	Incremental bool
+	// Unchanged 是服务器确认未变化、其中文章都已入库而跳过解析的列表页数
+	Unchanged int
+	Errors    []string
+	// Snapshots 是本次抓取的列表页在归档中的键，未配置 archive_dir 时为空
+	Snapshots []string
+	// Stored 表示本次抓取的文章已经入库，只有入库了的抓取才能推进增量来源的进度
+	Stored bool
+}
+
+// Failed 判断这次抓取是否完全失败：出现错误且没有解析出任何文章
+func (r SourceReport) Failed() bool {
+	return r.ItemsParsed == 0 && len(r.Errors) > 0
+}
+
+// Emitter 接收来源在列表页上解析出的结果
+type Emitter interface {
+	// Found 记录列表页中匹配到的条目数，不论之后是否解析成功
+	Found(n int)
+
+	// Emit 提交一篇解析成功的文章
+	Emit(article Article)
+
+	// Error 记录解析过程中的错误，会出现在运行报告中
+	Error(err error)
+}
+
+// sourceRun 记录一个来源在一次抓取中的文章和运行报告
+type sourceRun struct {
+	mu       sync.Mutex
+	source   Source
+	articles []Article
+	report   SourceReport
+}
+
+func newSourceRun(runID string, src Source) *sourceRun {
+	return &sourceRun{
+		source:   src,
+		articles: make([]Article, 0),
+		report: SourceReport{
+			RunID:       runID,
+			Source:      src.Name(),
+			StartedAt:   time.Now(),
+			URLsVisited: make([]string, 0),
+			Statuses:    make(map[int]int),
+			FieldCounts: make(map[string]int),
+			Errors:      make([]string, 0),
+		},
+	}
+}
+
+func (r *sourceRun) Found(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.report.ItemsFound += n
+}
+
+func (r *sourceRun) Emit(article Article) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// 文章始终归属于产生它的来源
+	article.Source = r.source.Name()
+	r.articles = append(r.articles, article)
+	r.report.ItemsParsed++
+	if article.ImageURL != "" {
+		r.report.FieldCounts[FieldImage]++
+	}
+	if article.Summary != "" {
+		r.report.FieldCounts[FieldSummary]++
+	}
+	if !article.PublishedAt.IsZero() {
+		r.report.FieldCounts[FieldPublished]++
+	}
+}
+
+func (r *sourceRun) Error(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.report.Errors = append(r.report.Errors, err.Error())
+}
+
+// unchanged 记录一个跳过解析的未变化列表页
+func (r *sourceRun) unchanged() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.report.Unchanged++
+}
+
+// snapshot 记录列表页快照的键
+func (r *sourceRun) snapshot(pageURL, key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.report.Snapshots = append(r.report.Snapshots, key)
+}
+
+// visited 判断URL是否已经发出过请求
+func (r *sourceRun) visited(u string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, visited := range r.report.URLsVisited {
+		if visited == u {
+			return true
+		}
+	}
+	return false
+}
+
+// track 在 collector 上注册回调，记录访问的URL、HTTP状态码和错误
+func (r *sourceRun) track(c *colly.Collector) {
+	c.OnRequest(func(req *colly.Request) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.report.URLsVisited = append(r.report.URLsVisited, req.URL.String())
+	})
+
+	c.OnResponse(func(resp *colly.Response) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.report.Statuses[resp.StatusCode]++
+	})
+
+	c.OnError(func(resp *colly.Response, err error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		if resp.StatusCode != 0 {
+			r.report.Statuses[resp.StatusCode]++
+		}
+		r.report.Errors = append(r.report.Errors, fmt.Sprintf("%s: %v", resp.Request.URL, err))
+	})
+}
+
+// finish 结束本次抓取并返回文章和报告
+func (r *sourceRun) finish() ([]Article, SourceReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.report.Duration = time.Since(r.report.StartedAt)
+	return r.articles, r.report
+}
@@ -0,0 +1,364 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 请求策略的默认值
+const (
+	DefaultFetchTimeout     = 20 * time.Second
+	DefaultMaxRetries       = 2
+	DefaultRetryBackoff     = 1 * time.Second
+	DefaultMaxBackoff       = 60 * time.Second
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Minute
+)
+
+// ErrCircuitOpen 表示来源的熔断器处于打开状态，请求未发出
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// FetchPolicy 描述请求的超时、重试与熔断策略
+//
+// 可以在配置文件顶层设置全局默认值，也可以在来源中单独覆盖，未设置的字段沿用上一级。
+type FetchPolicy struct {
+	// Timeout 单次请求（不含重试和排队等待限速）的超时
+	Timeout Duration `yaml:"timeout" json:"timeout"`
+
+	// MaxRetries 遇到网络错误、429 或 5xx 时的重试次数，设为 0 关闭重试
+	MaxRetries *int `yaml:"max_retries" json:"max_retries"`
+
+	// RetryBackoff 第一次重试前的等待时间，之后每次翻倍
+	RetryBackoff Duration `yaml:"retry_backoff" json:"retry_backoff"`
+
+	// MaxBackoff 单次等待的上限，退避时间最多增长到这里；服务器 Retry-After 要求更久时放弃重试
+	MaxBackoff Duration `yaml:"max_backoff" json:"max_backoff"`
+
+	// BreakerThreshold 连续失败多少次后熔断，暂停抓取该来源
+	BreakerThreshold int `yaml:"breaker_threshold" json:"breaker_threshold"`
+
+	// BreakerCooldown 熔断持续时间，之后放行一个试探请求，成功则恢复
+	BreakerCooldown Duration `yaml:"breaker_cooldown" json:"breaker_cooldown"`
+}
+
+// merge 用 override 中设置了的字段覆盖当前策略
+func (p FetchPolicy) merge(override FetchPolicy) FetchPolicy {
+	if override.Timeout > 0 {
+		p.Timeout = override.Timeout
+	}
+	if override.MaxRetries != nil {
+		p.MaxRetries = override.MaxRetries
+	}
+	if override.RetryBackoff > 0 {
+		p.RetryBackoff = override.RetryBackoff
+	}
+	if override.MaxBackoff > 0 {
+		p.MaxBackoff = override.MaxBackoff
+	}
+	if override.BreakerThreshold > 0 {
+		p.BreakerThreshold = override.BreakerThreshold
+	}
+	if override.BreakerCooldown > 0 {
+		p.BreakerCooldown = override.BreakerCooldown
+	}
+	return p
+}
+
+// withDefaults 为未设置的字段填入默认值
+func (p FetchPolicy) withDefaults() FetchPolicy {
+	retries := DefaultMaxRetries
+	return FetchPolicy{
+		Timeout:          Duration(DefaultFetchTimeout),
+		MaxRetries:       &retries,
+		RetryBackoff:     Duration(DefaultRetryBackoff),
+		MaxBackoff:       Duration(DefaultMaxBackoff),
+		BreakerThreshold: DefaultBreakerThreshold,
+		BreakerCooldown:  Duration(DefaultBreakerCooldown),
+	}.merge(p)
+}
+
+// attemptTimeoutKey 是请求 context 中单次请求超时的键
+type attemptTimeoutKey struct{}
+
+// timeoutTransport 为每次实际发出的请求施加超时
+//
+// 超时放在限速之下，这样排队等待限速的时间不计入超时；超时时长由上层的
+// retryTransport 通过 context 传入，覆盖读取响应正文的整个过程。
+type timeoutTransport struct {
+	base http.RoundTripper
+}
+
+func newTimeoutTransport(base http.RoundTripper) *timeoutTransport {
+	return &timeoutTransport{base: base}
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	timeout, ok := req.Context().Value(attemptTimeoutKey{}).(time.Duration)
+	if !ok || timeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose 在正文关闭时释放请求的 context
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// contextTransport 让 collector 发出的请求带上调用方的 context
+//
+// colly 创建的请求不带 context，取消只能通过 transport 传递下去。
+type contextTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
+
+func (t contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.base.RoundTrip(req.WithContext(t.ctx))
+}
+
+// retryTransport 属于单个来源，负责重试临时性失败并维护该来源的熔断器
+type retryTransport struct {
+	base    http.RoundTripper
+	policy  FetchPolicy
+	breaker *circuitBreaker
+}
+
+func newRetryTransport(base http.RoundTripper, source string, policy FetchPolicy) *retryTransport {
+	policy = policy.withDefaults()
+	name := source
+	if name == "" {
+		// 不属于任何来源的地址共用一个熔断器
+		name = "unknown source"
+	}
+	return &retryTransport{
+		base:    base,
+		policy:  policy,
+		breaker: newCircuitBreaker(name, policy.BreakerThreshold, time.Duration(policy.BreakerCooldown)),
+	}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.breaker.allow() {
+		return nil, fmt.Errorf("%w until %s", ErrCircuitOpen, t.breaker.state().RetryAt.Format(time.RFC3339))
+	}
+
+	ctx := context.WithValue(req.Context(), attemptTimeoutKey{}, time.Duration(t.policy.Timeout))
+	backoff := time.Duration(t.policy.RetryBackoff)
+	maxBackoff := time.Duration(t.policy.MaxBackoff)
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req.WithContext(ctx))
+
+		// 调用方取消或被 robots.txt 禁止说明不了站点是否正常
+		if req.Context().Err() != nil || errors.Is(err, ErrDisallowedByRobots) {
+			t.breaker.release()
+			return resp, err
+		}
+
+		reason := failureReason(resp, err)
+		if reason == nil {
+			t.breaker.record(nil)
+			return resp, err
+		}
+
+		// 退避时间封顶在 maxBackoff，服务器的 Retry-After 要求等待更久时放弃重试
+		wait := min(backoff+time.Duration(rand.Int63n(int64(backoff)/2+1)), maxBackoff)
+		tooLong := false
+		if resp != nil {
+			if after, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+				wait = after
+				tooLong = after > maxBackoff
+			}
+		}
+
+		if attempt >= *t.policy.MaxRetries || tooLong || !replayable(req) {
+			t.breaker.record(reason)
+			return resp, err
+		}
+
+		// 丢弃失败的响应，以便复用连接
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		log.Printf("Retrying %s in %s (attempt %d/%d): %v", req.URL, wait.Round(time.Millisecond), attempt+1, *t.policy.MaxRetries, reason)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			t.breaker.release()
+			return nil, req.Context().Err()
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// failureReason 判断请求结果是否是值得重试的临时性失败（网络错误、超时、429 与 5xx），
+// 是则返回失败原因，否则返回 nil
+func failureReason(resp *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return errors.New(resp.Status)
+	}
+	return nil
+}
+
+// replayable 判断请求能否重新发送，带正文的请求需要能重新获取正文
+func replayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// retryAfter 解析 Retry-After 头，支持秒数和HTTP日期两种写法
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		wait := time.Until(at)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+// 熔断器状态
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// BreakerState 是来源熔断器的当前状态
+type BreakerState struct {
+	Source              string
+	State               string
+	ConsecutiveFailures int
+	OpenedAt            time.Time
+	// RetryAt 熔断打开时，允许下一个试探请求的时间
+	RetryAt   time.Time
+	LastError string
+}
+
+// circuitBreaker 在来源连续失败后暂停对它的请求
+//
+// 打开 cooldown 时长后进入半开状态，只放行一个试探请求：成功则关闭，失败则再次打开。
+type circuitBreaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openedAt  time.Time
+	probing   bool
+	lastError string
+}
+
+func newCircuitBreaker(name string, threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{name: name, threshold: threshold, cooldown: cooldown}
+}
+
+// allow 判断现在是否可以发出请求
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.openedAt.IsZero() {
+		return true
+	}
+	if time.Since(b.openedAt) < b.cooldown || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// record 记录一次请求的结果，err 为 nil 表示成功
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		if !b.openedAt.IsZero() {
+			log.Printf("Circuit breaker of %s closed after a successful probe", b.name)
+		}
+		b.failures = 0
+		b.openedAt = time.Time{}
+		b.probing = false
+		return
+	}
+
+	b.failures++
+	b.lastError = err.Error()
+	if b.probing || (b.openedAt.IsZero() && b.failures >= b.threshold) {
+		log.Printf("Circuit breaker of %s open for %s after %d consecutive failures: %v", b.name, b.cooldown, b.failures, err)
+		b.openedAt = time.Now()
+		b.probing = false
+	}
+}
+
+// release 结束一次既不算成功也不算失败的请求，例如被调用方取消
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// state 返回熔断器的当前状态
+func (b *circuitBreaker) state() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := BreakerState{
+		Source:              b.name,
+		State:               BreakerClosed,
+		ConsecutiveFailures: b.failures,
+		LastError:           b.lastError,
+	}
+	if !b.openedAt.IsZero() {
+		state.State = BreakerOpen
+		state.OpenedAt = b.openedAt
+		state.RetryAt = b.openedAt.Add(b.cooldown)
+		if b.probing || !time.Now().Before(state.RetryAt) {
+			state.State = BreakerHalfOpen
+		}
+	}
+	return state
+}
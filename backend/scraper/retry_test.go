@@ -0,0 +1,287 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"0", 0, true},
+		{"120", 2 * time.Minute, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+	}
+
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("retryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	// HTTP 日期按距今的时长计算
+	got, ok := retryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	if !ok || got <= 58*time.Second || got > time.Minute {
+		t.Errorf("retryAfter(date in a minute) = %v, %v", got, ok)
+	}
+}
+
+// flakySite 按顺序返回 statuses 中的状态码，之后返回200，并记录每次请求的时间
+type flakySite struct {
+	mu         sync.Mutex
+	statuses   []int
+	retryAfter string
+	requests   []time.Time
+}
+
+func newFlakySite(t *testing.T, retryAfter string, statuses ...int) (*flakySite, *httptest.Server) {
+	site := &flakySite{statuses: statuses, retryAfter: retryAfter}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		site.mu.Lock()
+		defer site.mu.Unlock()
+		site.requests = append(site.requests, time.Now())
+		if len(site.statuses) > 0 {
+			status := site.statuses[0]
+			site.statuses = site.statuses[1:]
+			if site.retryAfter != "" {
+				w.Header().Set("Retry-After", site.retryAfter)
+			}
+			w.WriteHeader(status)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return site, server
+}
+
+func (s *flakySite) gaps() []time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	gaps := make([]time.Duration, 0)
+	for i := 1; i < len(s.requests); i++ {
+		gaps = append(gaps, s.requests[i].Sub(s.requests[i-1]))
+	}
+	return gaps
+}
+
+func retryPolicy(retries int, backoff, maxBackoff time.Duration) FetchPolicy {
+	return FetchPolicy{
+		MaxRetries:       &retries,
+		RetryBackoff:     Duration(backoff),
+		MaxBackoff:       Duration(maxBackoff),
+		BreakerThreshold: 10,
+	}
+}
+
+func roundTrip(t *testing.T, transport http.RoundTripper, req *http.Request) (int, error) {
+	t.Helper()
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func TestRetryBackoff(t *testing.T) {
+	site, server := newFlakySite(t, "", 503, 503, 503, 503)
+	// 退避时间 10ms、20ms 之后封顶在 25ms，不会因为超过上限放弃重试
+	transport := newRetryTransport(http.DefaultTransport, "Example", retryPolicy(4, 10*time.Millisecond, 25*time.Millisecond))
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	status, err := roundTrip(t, transport, req)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("RoundTrip() = %d, %v, want 200 after retries", status, err)
+	}
+
+	gaps := site.gaps()
+	want := []time.Duration{10, 20, 25, 25}
+	if len(gaps) != len(want) {
+		t.Fatalf("made %d retries, want %d", len(gaps), len(want))
+	}
+	for i, gap := range gaps {
+		if gap < want[i]*time.Millisecond {
+			t.Errorf("wait before retry %d = %v, want at least %dms", i+1, gap, want[i])
+		}
+	}
+	if state := transport.breaker.state(); state.ConsecutiveFailures != 0 {
+		t.Errorf("breaker counted %d failures after a success", state.ConsecutiveFailures)
+	}
+
+	// 重试次数用完后返回最后的失败响应
+	site, server = newFlakySite(t, "", 500, 500, 500)
+	transport = newRetryTransport(http.DefaultTransport, "Example", retryPolicy(1, time.Millisecond, time.Millisecond))
+	req, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+	if status, err := roundTrip(t, transport, req); err != nil || status != http.StatusInternalServerError {
+		t.Errorf("RoundTrip() = %d, %v, want the last 500", status, err)
+	}
+	if n := len(site.gaps()) + 1; n != 2 {
+		t.Errorf("made %d requests, want 2", n)
+	}
+	if state := transport.breaker.state(); state.ConsecutiveFailures != 1 {
+		t.Errorf("breaker counted %d failures, want 1", state.ConsecutiveFailures)
+	}
+
+	// 其他状态码不重试
+	site, server = newFlakySite(t, "", 404)
+	req, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+	if status, _ := roundTrip(t, transport, req); status != http.StatusNotFound || len(site.gaps()) != 0 {
+		t.Errorf("404 = %d after %d retries", status, len(site.gaps()))
+	}
+}
+
+func TestRetryAfterLimit(t *testing.T) {
+	// Retry-After 不超过上限时按它等待
+	site, server := newFlakySite(t, "0", 429)
+	transport := newRetryTransport(http.DefaultTransport, "Example", retryPolicy(2, time.Second, time.Second))
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	start := time.Now()
+	if status, err := roundTrip(t, transport, req); err != nil || status != http.StatusOK {
+		t.Errorf("RoundTrip() = %d, %v, want 200", status, err)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("Retry-After: 0 waited %v", elapsed)
+	}
+
+	// Retry-After 要求等待超过上限时放弃重试
+	site, server = newFlakySite(t, "120", 503)
+	req, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+	if status, err := roundTrip(t, transport, req); err != nil || status != http.StatusServiceUnavailable {
+		t.Errorf("RoundTrip() = %d, %v, want 503", status, err)
+	}
+	if len(site.gaps()) != 0 {
+		t.Errorf("retried %d times despite a Retry-After above max_backoff", len(site.gaps()))
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	cooldown := 20 * time.Millisecond
+	b := newCircuitBreaker("Example", 2, cooldown)
+	failure := errors.New("503 Service Unavailable")
+
+	// 连续失败达到阈值后打开
+	b.record(failure)
+	if state := b.state(); state.State != BreakerClosed || !b.allow() {
+		t.Fatalf("state after one failure = %s", state.State)
+	}
+	b.record(failure)
+	state := b.state()
+	if state.State != BreakerOpen || state.ConsecutiveFailures != 2 || state.LastError != failure.Error() || b.allow() {
+		t.Fatalf("state after two failures = %+v", state)
+	}
+
+	// 冷却之后半开，只放行一个试探请求
+	time.Sleep(cooldown)
+	if state := b.state(); state.State != BreakerHalfOpen {
+		t.Fatalf("state after the cooldown = %s, want %s", state.State, BreakerHalfOpen)
+	}
+	if !b.allow() {
+		t.Fatal("probe was not allowed")
+	}
+	if b.allow() {
+		t.Fatal("second request allowed while probing")
+	}
+
+	// 试探请求被取消时放行下一个试探请求
+	b.release()
+	if !b.allow() {
+		t.Fatal("probe was not allowed after a released probe")
+	}
+
+	// 试探失败再次打开
+	b.record(failure)
+	if state := b.state(); state.State != BreakerOpen || b.allow() {
+		t.Fatalf("state after a failed probe = %s", state.State)
+	}
+
+	// 试探成功则关闭
+	time.Sleep(cooldown)
+	if !b.allow() {
+		t.Fatal("probe was not allowed")
+	}
+	b.record(nil)
+	state = b.state()
+	if state.State != BreakerClosed || state.ConsecutiveFailures != 0 || !b.allow() {
+		t.Errorf("state after a successful probe = %+v", state)
+	}
+}
+
+func TestRetryTransportBreaker(t *testing.T) {
+	site, server := newFlakySite(t, "", 503, 503)
+	policy := retryPolicy(0, time.Millisecond, time.Millisecond)
+	policy.BreakerThreshold = 2
+	policy.BreakerCooldown = Duration(20 * time.Millisecond)
+	transport := newRetryTransport(http.DefaultTransport, "Example", policy)
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		roundTrip(t, transport, req)
+	}
+	// 熔断期间请求不发出
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	if _, err := roundTrip(t, transport, req); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("RoundTrip() while open error = %v, want ErrCircuitOpen", err)
+	}
+	if n := len(site.gaps()) + 1; n != 2 {
+		t.Errorf("made %d requests, want 2", n)
+	}
+
+	// 被 robots.txt 禁止的试探请求不算失败，也不占住试探名额
+	time.Sleep(20 * time.Millisecond)
+	disallowed := newRetryTransport(roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, ErrDisallowedByRobots
+	}), "Example", policy)
+	disallowed.breaker = transport.breaker
+	req, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+	if _, err := roundTrip(t, disallowed, req); !errors.Is(err, ErrDisallowedByRobots) {
+		t.Fatalf("disallowed RoundTrip() error = %v", err)
+	}
+	if state := transport.breaker.state(); state.State != BreakerHalfOpen || state.ConsecutiveFailures != 2 {
+		t.Fatalf("state after a disallowed probe = %+v", state)
+	}
+
+	// 试探成功后恢复
+	req, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+	if status, err := roundTrip(t, transport, req); err != nil || status != http.StatusOK {
+		t.Fatalf("probe = %d, %v", status, err)
+	}
+	if state := transport.breaker.state(); state.State != BreakerClosed {
+		t.Errorf("state after a successful probe = %s", state.State)
+	}
+}
+
+func TestRetryTransportCancelledProbe(t *testing.T) {
+	_, server := newFlakySite(t, "", 503, 503, 503)
+	policy := retryPolicy(2, time.Minute, time.Minute)
+	policy.BreakerThreshold = 1
+	policy.BreakerCooldown = Duration(time.Millisecond)
+	transport := newRetryTransport(http.DefaultTransport, "Example", policy)
+
+	transport.breaker.record(errors.New("503 Service Unavailable"))
+	time.Sleep(2 * time.Millisecond)
+
+	// 试探请求在退避等待中被取消，不算失败，下一个试探请求可以发出
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if _, err := roundTrip(t, transport, req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("cancelled probe error = %v", err)
+	}
+	if state := transport.breaker.state(); state.ConsecutiveFailures != 1 || state.State != BreakerHalfOpen {
+		t.Errorf("state after a cancelled probe = %+v", state)
+	}
+	if !transport.breaker.allow() {
+		t.Error("next probe was not allowed after a cancelled probe")
+	}
+}
@@ -1,9 +1,14 @@
 package scraper
 
 import (
-	"crypto/md5"
+	"context"
 	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 	"github.com/gocolly/colly/v2"
 )
@@ -16,200 +21,543 @@ type Article struct {
 	ImageURL    string
 	Summary     string
 	Source      string
+	Author      string
 	PublishedAt time.Time
+	
+	// ModifiedAt 与 CanonicalURL 来自详情页的结构化元信息
+	ModifiedAt   time.Time
+	CanonicalURL string
+	
+	// ListingSnapshot 是文章所在列表页原始内容在归档中的键，未配置 archive_dir 时为空
+	ListingSnapshot string
+}
+
+// ArticleDetails 是详情页的抓取结果
+type ArticleDetails struct {
+	Content  string
+	Metadata Metadata
+	
+	// Snapshot 是详情页原始HTML在归档中的键，未配置 archive_dir 时为空
+	Snapshot string
+}
+
+// WithMetadata 用详情页元信息补全文章字段
+//
+// 列表页没有提供的标题、发布时间、作者和摘要由元信息补齐；元信息中的主图通常比
+// 列表页缩略图更准确，因此优先使用。
+func (a Article) WithMetadata(m Metadata) Article {
+	if a.Title == "" {
+		a.Title = m.Title
+	}
+	if a.PublishedAt.IsZero() {
+		a.PublishedAt = m.PublishedAt
+	}
+	if a.Author == "" {
+		a.Author = m.Author
+	}
+	if a.Summary == "" {
+		a.Summary = m.Description
+	}
+	if m.ImageURL != "" {
+		a.ImageURL = m.ImageURL
+	}
+	a.ModifiedAt = m.ModifiedAt
+	if m.CanonicalURL != "" {
+		a.CanonicalURL = CanonicalizeURL(m.CanonicalURL)
+	}
+	return a
 }
 
 // Scraper handles news scraping
 type Scraper struct {
-	collector *colly.Collector
-	articles  []Article
+	articles      []Article
+	sources       *Registry
+	detailWorkers int
+	sourceWorkers int
+	userAgent     string
+	
+	// transport 在所有 collector 之间共享，负责 robots.txt 与按域名限速；
+	// fixture 模式下直接从本地目录读取页面
+	transport http.RoundTripper
+	
+	// fetchers 是每个来源各自的重试与熔断层，包在共享的 transport 之外
+	fetchPolicy    FetchPolicy
+	sourcePolicies map[string]FetchPolicy
+	fetchersMu     sync.Mutex
+	fetchers       map[string]*retryTransport
+	
+	// sourceCharsets 是配置中指定了页面编码的来源
+	sourceCharsets map[string]string
+	
+	// budget 限制所有来源同时进行的请求总数，并在来源之间公平分配
+	budget *requestBudget
+	
+	// cache 是磁盘HTTP缓存，未配置 cache_dir 时为 nil
+	cache *cacheTransport
+	
+	// archive 保存抓取到的原始页面，未配置 archive_dir 时为 nil
+	archive *snapshotArchive
+	
+	// lastRuns 记录每个来源上一次文章成功入库的抓取的开始时间，供增量来源使用
+	lastRunsMu sync.Mutex
+	lastRuns   map[string]time.Time
+	
+	// pending 是按来源记录的详情页抓取失败、等待重新提交的文章
+	pendingMu sync.Mutex
+	pending   map[string]map[string]*pendingArticle
 }
 
 // NewScraper creates a new Scraper instance
+//
+// 来源配置从 SCRAPER_CONFIG 指定的文件加载，未设置时使用 DefaultConfigPath。
+// 所有来源都来自配置文件，加载失败时直接退出，以免服务在没有任何来源的情况下运行。
 func NewScraper() *Scraper {
+	path := os.Getenv("SCRAPER_CONFIG")
+	if path == "" {
+		path = DefaultConfigPath
+	}
+	
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		log.Fatalf("Failed to load scraper config %s: %v", path, err)
+	}
+	
+	return NewScraperWithConfig(cfg)
+}
+
+// NewScraperWithConfig 使用给定的来源配置创建 Scraper
+func NewScraperWithConfig(cfg *Config) *Scraper {
+	// 复制默认注册表并加入配置文件中的来源
+	sources := defaultRegistry.clone()
+	for _, srcCfg := range cfg.Sources {
+		sources.Register(srcCfg.Build())
+		if !srcCfg.IsEnabled() {
+			sources.Disable(srcCfg.Name)
+		}
+	}
+	
+	// 按环境变量禁用来源（逗号分隔的来源名称）
+	for _, name := range strings.Split(os.Getenv("SCRAPER_DISABLED_SOURCES"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			sources.Disable(name)
+		}
+	}
+	
+	detailWorkers := cfg.DetailWorkers
+	if detailWorkers <= 0 {
+		detailWorkers = DefaultDetailWorkers
+	}
+	
+	sourceWorkers := cfg.SourceWorkers
+	if sourceWorkers <= 0 {
+		sourceWorkers = DefaultSourceWorkers
+	}
+	
+	maxRequests := cfg.MaxRequests
+	if maxRequests <= 0 {
+		maxRequests = DefaultMaxRequests
+	}
+	
+	userAgent := cfg.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	
+	// 未配置默认策略时沿用原来的限制：每个域名并发2个请求，间隔1秒
+	politeness := cfg.Politeness
+	if politeness.Default == (DomainPolicy{}) {
+		politeness.Default = DomainPolicy{Parallelism: 2, Delay: Duration(1 * time.Second)}
+	}
+	respectRobots := cfg.RespectRobotsTxt == nil || *cfg.RespectRobotsTxt
+	
+	sourcePolicies := make(map[string]FetchPolicy)
+	sourceCharsets := make(map[string]string)
+	for _, srcCfg := range cfg.Sources {
+		sourcePolicies[srcCfg.Name] = cfg.Fetch.merge(srcCfg.Fetch)
+		if srcCfg.Charset != "" {
+			sourceCharsets[srcCfg.Name] = srcCfg.Charset
+		}
+	}
+	
+	var transport http.RoundTripper
+	var cache *cacheTransport
+	if cfg.Mode == ModeFixture {
+		// fixture 模式不访问网络，也就不需要 robots.txt、限速与缓存
+		log.Printf("Scraper running in fixture mode, serving pages from %s", cfg.FixtureDir)
+		transport = newFixtureTransport(cfg.FixtureDir)
+	} else {
+		// 配置了缓存目录时使用条件请求，未变化的页面直接从磁盘读取；
+		// 单次请求的超时施加在限速之下，排队等待不计入超时
+		var base http.RoundTripper = newTimeoutTransport(http.DefaultTransport)
+		if cfg.CacheDir != "" {
+			cache = newCacheTransport(base, cfg.CacheDir, time.Duration(cfg.CacheMaxAge))
+			base = cache
+		}
+		transport = newPoliteTransport(base, userAgent, politeness, respectRobots)
+	}
+	
+	s := &Scraper{
+		articles:       make([]Article, 0),
+		sources:        sources,
+		detailWorkers:  detailWorkers,
+		sourceWorkers:  sourceWorkers,
+		userAgent:      userAgent,
+		transport:      transport,
+		fetchPolicy:    cfg.Fetch,
+		sourcePolicies: sourcePolicies,
+		fetchers:       make(map[string]*retryTransport),
+		sourceCharsets: sourceCharsets,
+		budget:         newRequestBudget(maxRequests),
+		cache:          cache,
+		lastRuns:       make(map[string]time.Time),
+		pending:        make(map[string]map[string]*pendingArticle),
+	}
+	if cfg.ArchiveDir != "" {
+		s.archive = newSnapshotArchive(cfg.ArchiveDir)
+	}
+	
+	return s
+}
+
+// newCollector 创建一个全新的 collector，请求使用 source 的超时、重试与熔断策略
+//
+// 每次抓取运行中的每个来源、每次详情页抓取都使用独立的 collector，
+// 避免回调在多次运行之间累积，也避免已访问记录导致列表页不再被抓取。
+// ctx 取消后不再发出新请求，进行中的请求、重试等待和限速排队也会立即中止。
+func (s *Scraper) newCollector(ctx context.Context, source string) *colly.Collector {
 	c := colly.NewCollector(
 		colly.MaxDepth(2),
 	)
 	
-	// 设置用户代理
-	c.UserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"
+	// 设置用户代理，如实表明爬虫身份
+	c.UserAgent = s.userAgent
 	
-	// 限制请求频率，避免被网站屏蔽
-	c.Limit(&colly.LimitRule{
-		DomainGlob:  "*",
-		Parallelism: 2,
-		Delay:       1 * time.Second,
+	// robots.txt 与按域名限速由共享的 transport 处理，
+	// 这样详情页并发抓取时各个 collector 也遵守同一套限制；
+	// 页面统一转换为 UTF-8 后再交给 colly 解析
+	fetcher := charsetTransport{base: s.fetcher(source), charset: s.sourceCharsets[source]}
+	c.WithTransport(contextTransport{ctx: ctx, base: fetcher})
+	c.OnRequest(func(r *colly.Request) {
+		if ctx.Err() != nil {
+			r.Abort()
+		}
 	})
 	
-	return &Scraper{
-		collector: c,
-		articles:  make([]Article, 0),
-	}
+	// 超时由 transport 按单次请求施加，整体超时会打断重试等待
+	c.SetRequestTimeout(0)
+	
+	return c
 }
 
-// ScrapeGames collects game news from various sources
-func (s *Scraper) ScrapeGames() ([]Article, error) {
-	articles := make([]Article, 0)
+// fetcher 返回来源的重试与熔断层，没有单独配置的来源使用全局策略
+//
+// 每次重试都重新占用全局请求名额，退避等待期间不占用。
+func (s *Scraper) fetcher(source string) *retryTransport {
+	s.fetchersMu.Lock()
+	defer s.fetchersMu.Unlock()
 	
-	// 抓取GameSpot的游戏新闻
-	s.scrapeGameSpot(&articles)
-	
-	// 抓取IGN的游戏新闻
-	s.scrapeIGN(&articles)
-	
-	// 如果没有成功抓取到任何文章，则使用模拟数据
-	if len(articles) == 0 {
-		mockArticles := []Article{
-			{
-				ID:          fmt.Sprintf("%x", md5.Sum([]byte("New Game Update Coming Soon")))[0:8],
-				Title:       "New Game Update Coming Soon",
-				URL:         "https://example.com/news/new-game-update-coming-soon",
-				ImageURL:    "https://picsum.photos/600/400?random=1",
-				Summary:     "Developers announce major update with new features and improvements.",
-				Source:      "GameNews Network",
-				PublishedAt: time.Now().Add(-24 * time.Hour),
-			},
-			{
-				ID:          fmt.Sprintf("%x", md5.Sum([]byte("Esports Tournament Results Are Out")))[0:8],
-				Title:       "Esports Tournament Results Are Out",
-				URL:         "https://example.com/news/esports-tournament-results",
-				ImageURL:    "https://picsum.photos/600/400?random=2",
-				Summary:     "The year's biggest esports tournament has ended, with the champion team winning a million-dollar prize.",
-				Source:      "eSports Daily",
-				PublishedAt: time.Now().Add(-48 * time.Hour),
-			},
-		}
-		
-		articles = append(articles, mockArticles...)
+	if f, ok := s.fetchers[source]; ok {
+		return f
 	}
 	
-	return articles, nil
+	policy, ok := s.sourcePolicies[source]
+	if !ok {
+		policy = s.fetchPolicy
+	}
+	base := &budgetTransport{base: s.transport, budget: s.budget, source: source}
+	f := newRetryTransport(base, source, policy)
+	s.fetchers[source] = f
+	return f
 }
 
-// scrapeGameSpot 抓取GameSpot的游戏新闻
-func (s *Scraper) scrapeGameSpot(articles *[]Article) {
-	s.collector.OnHTML("article.media", func(e *colly.HTMLElement) {
-		defer func() {
-			if r := recover(); r != nil {
-				// 忽略解析错误
-			}
-		}()
-		
-		title := e.ChildText("h3 a")
-		link := e.ChildAttr("h3 a", "href")
-		summary := e.ChildText("p")
-		image := e.ChildAttr("img", "src")
-		
-		// 完整链接
-		if link != "" && !strings.HasPrefix(link, "http") {
-			link = "https://www.gamespot.com" + link
-		}
-		
-		// 完整图片链接
-		if image != "" && !strings.HasPrefix(image, "http") {
-			image = "https://www.gamespot.com" + image
-		}
-		
-		if title != "" && link != "" {
-			article := Article{
-				ID:          fmt.Sprintf("%x", md5.Sum([]byte(link)))[0:8],
-				Title:       strings.TrimSpace(title),
-				URL:         link,
-				ImageURL:    image,
-				Summary:     strings.TrimSpace(summary),
-				Source:      "GameSpot",
-				PublishedAt: time.Now(),
-			}
-			*articles = append(*articles, article)
+// BreakerStates 返回各来源熔断器的当前状态，按来源名称排序
+func (s *Scraper) BreakerStates() []BreakerState {
+	s.fetchersMu.Lock()
+	defer s.fetchersMu.Unlock()
+	
+	states := make([]BreakerState, 0, len(s.fetchers))
+	for source, f := range s.fetchers {
+		if source != "" {
+			states = append(states, f.breaker.state())
 		}
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Source < states[j].Source
 	})
-	
-	// 访问GameSpot游戏新闻页面
-	s.collector.Visit("https://www.gamespot.com/news/")
+	return states
+}
+
+// PruneCache 删除HTTP缓存中超过 cache_max_age 没有被使用的条目，返回删除的条目数
+func (s *Scraper) PruneCache() (int, error) {
+	if s.cache == nil {
+		return 0, nil
+	}
+	return s.cache.prune()
+}
+
+// Sources 返回该爬虫使用的来源注册表，可用于启用或禁用来源
+func (s *Scraper) Sources() *Registry {
+	return s.sources
 }
 
-// scrapeIGN 抓取IGN的游戏新闻
-func (s *Scraper) scrapeIGN(articles *[]Article) {
-	s.collector.OnHTML("article", func(e *colly.HTMLElement) {
-		defer func() {
-			if r := recover(); r != nil {
-				// 忽略解析错误
+// ScrapeGames collects game news from various sources
+func (s *Scraper) ScrapeGames() ([]Article, error) {
+	return s.ScrapeGamesContext(context.Background())
+}
+
+// ScrapeGamesContext 与 ScrapeGames 相同，ctx 取消或超时时中止抓取
+func (s *Scraper) ScrapeGamesContext(ctx context.Context) ([]Article, error) {
+	articles, _, err := s.ScrapeGamesWithReportsContext(ctx)
+	return articles, err
+}
+
+// ScrapeGamesWithReports 抓取所有已启用的来源，并返回每个来源的运行报告
+//
+// 所有来源都失败时返回错误。
+func (s *Scraper) ScrapeGamesWithReports() ([]Article, []SourceReport, error) {
+	return s.ScrapeGamesWithReportsContext(context.Background())
+}
+
+// ScrapeGamesWithReportsContext 与 ScrapeGamesWithReports 相同，ctx 取消或超时时
+// 中止进行中的请求并返回 ctx 的错误，此时的结果不完整，不应入库
+//
+// 来源由 source_workers 个协程并发抓取，慢的来源不会拖住其他来源；所有请求共享
+// max_requests 个全局名额，同一域名仍遵守各自的并发与间隔限制。
+// 返回的文章与报告按来源的注册顺序排列。
+func (s *Scraper) ScrapeGamesWithReportsContext(ctx context.Context) ([]Article, []SourceReport, error) {
+	sources := s.sources.Enabled()
+	runID := fmt.Sprintf("%d", time.Now().UnixNano())
+	
+	sourceArticles := make([][]Article, len(sources))
+	sourceReports := make([]SourceReport, len(sources))
+	
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < s.sourceWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() == nil {
+					sourceArticles[i], sourceReports[i] = s.scrapeSource(ctx, runID, sources[i])
+				}
 			}
 		}()
+	}
+	
+	for i := range sources {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	
+	// 被中断的来源没有完整的结果，整轮作废
+	if err := ctx.Err(); err != nil {
+		return make([]Article, 0), make([]SourceReport, 0), err
+	}
+	
+	articles := make([]Article, 0)
+	reports := make([]SourceReport, 0, len(sources))
+	failed := 0
+	for i, report := range sourceReports {
+		articles = append(articles, sourceArticles[i]...)
+		reports = append(reports, report)
 		
-		// 查找文章标题
-		title := e.ChildText("h3 a")
-		if title == "" {
-			title = e.ChildText("h2 a")
-		}
-		if title == "" {
-			title = e.ChildText("h1 a")
-		}
-		
-		// 查找文章链接
-		link := e.ChildAttr("h3 a", "href")
-		if link == "" {
-			link = e.ChildAttr("h2 a", "href")
-		}
-		if link == "" {
-			link = e.ChildAttr("h1 a", "href")
-		}
-		
-		summary := e.ChildText("p")
-		image := e.ChildAttr("img", "src")
-		
-		// 完整链接
-		if link != "" && !strings.HasPrefix(link, "http") {
-			link = "https://www.ign.com" + link
+		if report.Failed() {
+			failed++
 		}
-		
-		if title != "" && link != "" {
-			article := Article{
-				ID:          fmt.Sprintf("%x", md5.Sum([]byte(link)))[0:8],
-				Title:       strings.TrimSpace(title),
-				URL:         link,
-				ImageURL:    image,
-				Summary:     strings.TrimSpace(summary),
-				Source:      "IGN",
-				PublishedAt: time.Now(),
-			}
-			*articles = append(*articles, article)
+	}
+	
+	var err error
+	if failed > 0 && failed == len(reports) {
+		err = fmt.Errorf("all %d sources failed", failed)
+	}
+	
+	return articles, reports, err
+}
+
+// scrapeSource 使用独立的 collector 抓取单个来源的列表页
+func (s *Scraper) scrapeSource(ctx context.Context, runID string, src Source) ([]Article, SourceReport) {
+	c := s.newCollector(ctx, src.Name())
+	run := newSourceRun(runID, src)
+	
+	// 熔断期间不访问该来源，只在报告中记录
+	if state := s.fetcher(src.Name()).breaker.state(); state.State == BreakerOpen {
+		run.Error(fmt.Errorf("%w until %s: %s", ErrCircuitOpen, state.RetryAt.Format(time.RFC3339), state.LastError))
+		return run.finish()
+	}
+	
+	run.track(c)
+	
+	// 增量来源本就只提交新文章，sitemap 索引未变化时其中的子 sitemap 也可能已经更新，
+	// 因此只对普通列表页跳过未变化的页面
+	incremental, isIncremental := src.(IncrementalSource)
+	if !isIncremental {
+		s.skipUnchanged(c, run)
+	}
+	s.archivePages(c, run.snapshot)
+	
+	if isIncremental {
+		run.report.Incremental = true
+		incremental.ParseListingSince(c, run, s.LastRun(src.Name()))
+	} else {
+		src.ParseListing(c, run)
+	}
+	
+	for _, listingURL := range src.Discover() {
+		// 请求失败已由 OnError 记录，这里只记录未发出请求的错误（例如重复访问）
+		if err := c.Visit(listingURL); err != nil && !run.visited(listingURL) {
+			run.Error(fmt.Errorf("%s: %w", listingURL, err))
 		}
-	})
+	}
+	c.Wait()
+	
+	articles, report := run.finish()
+	return s.withPending(src.Name(), articles), report
+}
+
+// LastRun 返回来源上一次文章成功入库的抓取的开始时间，没有记录时返回零值
+func (s *Scraper) LastRun(source string) time.Time {
+	s.lastRunsMu.Lock()
+	defer s.lastRunsMu.Unlock()
+	
+	return s.lastRuns[source]
+}
+
+// SetLastRun 设置来源上一次文章成功入库的抓取的开始时间
+//
+// 由调用方在本次抓取的文章入库之后调用，入库失败或被中断的抓取不推进进度，
+// 否则 sitemap 等增量来源会永远错过这段时间内更新的条目。服务重启时可用保存的
+// 运行报告恢复，使增量来源从上次停下的地方继续，而不是重新回溯。
+func (s *Scraper) SetLastRun(source string, startedAt time.Time) {
+	s.lastRunsMu.Lock()
+	defer s.lastRunsMu.Unlock()
 	
-	// 访问IGN游戏新闻页面
-	s.collector.Visit("https://www.ign.com/news")
+	if startedAt.After(s.lastRuns[source]) {
+		s.lastRuns[source] = startedAt
+	}
+}
+
+// newArticle 根据列表页解析出的字段创建文章
+//
+// 链接会去掉跟踪参数等噪音；发布时间留空，由列表页的日期或详情页元信息填充。
+func newArticle(title, link, image, summary, source string) Article {
+	return Article{
+		ID:       ArticleID(link),
+		Title:    strings.TrimSpace(title),
+		URL:      CanonicalizeURL(link),
+		ImageURL: image,
+		Summary:  strings.TrimSpace(summary),
+		Source:   source,
+	}
 }
 
 // ScrapeGameDetails 从文章URL抓取详细内容
 func (s *Scraper) ScrapeGameDetails(url string) (string, error) {
-	var content string
+	return s.ScrapeGameDetailsContext(context.Background(), url)
+}
+
+// ScrapeGameDetailsContext 与 ScrapeGameDetails 相同，ctx 取消或超时时中止请求
+func (s *Scraper) ScrapeGameDetailsContext(ctx context.Context, url string) (string, error) {
+	details, err := s.ScrapeArticleDetailsContext(ctx, url)
+	return details.Content, err
+}
+
+// ScrapeDetails 使用有限数量的协程并发抓取多篇文章的详情页
+//
+// 返回的结果与错误按下标和 articles 一一对应。
+func (s *Scraper) ScrapeDetails(articles []Article) ([]ArticleDetails, []error) {
+	return s.ScrapeDetailsContext(context.Background(), articles)
+}
+
+// ScrapeDetailsContext 与 ScrapeDetails 相同，ctx 取消后尚未开始的文章直接以 ctx 的错误返回
+//
+// 各来源的文章轮流分发，一个来源的大量文章不会排在其他来源前面。
+func (s *Scraper) ScrapeDetailsContext(ctx context.Context, articles []Article) ([]ArticleDetails, []error) {
+	details := make([]ArticleDetails, len(articles))
+	errs := make([]error, len(articles))
+	
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < s.detailWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					continue
+				}
+				details[i], errs[i] = s.ScrapeArticleDetailsContext(ctx, articles[i].URL)
+			}
+		}()
+	}
 	
-	// 创建新的collector用于抓取详情页
-	detailCollector := colly.NewCollector()
+	for _, i := range interleaveBySource(articles) {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
 	
-	detailCollector.OnHTML("div.news-content, div.article-content, div.content, article", func(e *colly.HTMLElement) {
-		content = e.Text
+	return details, errs
+}
+
+// ScrapeArticleDetails 从文章URL抓取正文以及 JSON-LD、Open Graph 等元信息
+func (s *Scraper) ScrapeArticleDetails(url string) (ArticleDetails, error) {
+	return s.ScrapeArticleDetailsContext(context.Background(), url)
+}
+
+// ScrapeArticleDetailsContext 与 ScrapeArticleDetails 相同，ctx 取消或超时时中止请求
+func (s *Scraper) ScrapeArticleDetailsContext(ctx context.Context, url string) (ArticleDetails, error) {
+	// 创建新的collector用于抓取详情页，使用文章所属来源的请求策略
+	var source string
+	if src, ok := s.sources.ForURL(url); ok {
+		source = src.Name()
+	}
+	detailCollector := s.newCollector(ctx, source)
+	
+	// 配置了归档目录时保存原始页面，以便之后离线重新解析
+	var snapshot string
+	s.archivePages(detailCollector, func(pageURL, key string) {
+		snapshot = key
 	})
 	
-	// 如果没有找到特定内容，抓取body文本
-	detailCollector.OnHTML("body", func(e *colly.HTMLElement) {
-		if content == "" {
-			content = e.Text
-		}
+	details, err := s.scrapeArticleDetails(detailCollector, url)
+	details.Snapshot = snapshot
+	return details, err
+}
+
+// scrapeArticleDetails 在给定的 collector 上解析详情页，
+// 真实抓取与重新解析归档快照共用这一流程
+func (s *Scraper) scrapeArticleDetails(detailCollector *colly.Collector, url string) (ArticleDetails, error) {
+	var details ArticleDetails
+	
+	emit := func(text string) {
+		details.Content = text
+	}
+	
+	// 结构化元信息与来源无关，统一从整个文档中提取
+	detailCollector.OnHTML("html", func(e *colly.HTMLElement) {
+		details.Metadata = extractMetadata(e.DOM, e.Request.URL.String())
 	})
 	
-	err := detailCollector.Visit(url)
-	if err != nil {
-		// 如果抓取失败，返回默认内容
-		content = "This is the full content of the news article. In a real implementation, this would be scraped from the source website. " +
-			"Developers today officially announced that the highly anticipated game update will be released next month. " +
-			"This update will include brand new maps, characters, and gameplay mechanics, promising to deliver a completely new gaming experience. " +
-			"The development team said they spent over a year perfecting these new features and conducted multiple rounds of testing to ensure game balance.\n\n" +
-			"Additional details about the update include new quests, improved graphics, and enhanced multiplayer capabilities. " +
-			"Players can expect a significant boost in performance and new customization options for their characters. " +
-			"The update will be free for all existing players and will be rolled out in phases to ensure server stability."
-	}
-	
-	return content, nil
-}
\ No newline at end of file
+	// 优先使用文章所属来源的详情解析规则
+	if src, ok := s.sources.ForURL(url); ok {
+		src.ParseDetail(detailCollector, emit)
+	} else {
+		parseDefaultDetail(detailCollector, emit)
+	}
+	
+	if err := detailCollector.Visit(url); err != nil {
+		return details, fmt.Errorf("fetch %s: %w", url, err)
+	}
+	
+	return details, nil
+}
+
+// parseDefaultDetail 通用的详情页解析规则，按文本密度与链接密度提取正文
+func parseDefaultDetail(c *colly.Collector, emit func(content string)) {
+	c.OnHTML("html", func(e *colly.HTMLElement) {
+		emit(extractMainContent(e.DOM))
+	})
+}
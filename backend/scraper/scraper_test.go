@@ -0,0 +1,55 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestScrapeCancelled(t *testing.T) {
+	started := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	s := NewScraperWithConfig(&Config{
+		Politeness: PolitenessConfig{Default: DomainPolicy{Parallelism: 2}},
+		Sources: []SourceConfig{
+			{Name: "Slow", ListingURL: StringList{server.URL + "/slow"}, Selectors: SelectorConfig{Item: "div", Title: StringList{"a"}, Link: StringList{"a"}}},
+			{Name: "Other", ListingURL: StringList{server.URL + "/other"}, Selectors: SelectorConfig{Item: "div", Title: StringList{"a"}, Link: StringList{"a"}}},
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	start := time.Now()
+	articles, reports, err := s.ScrapeGamesWithReportsContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ScrapeGamesWithReportsContext() error = %v, want context.Canceled", err)
+	}
+	if len(articles) != 0 || len(reports) != 0 {
+		t.Errorf("interrupted run returned %d articles and %d reports", len(articles), len(reports))
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("interrupted run took %v", elapsed)
+	}
+
+	// 中断不是站点故障，不计入熔断
+	for _, state := range s.BreakerStates() {
+		if state.ConsecutiveFailures != 0 {
+			t.Errorf("breaker of %s counted %d failures", state.Source, state.ConsecutiveFailures)
+		}
+	}
+}
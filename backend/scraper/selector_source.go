@@ -0,0 +1,151 @@
+package scraper
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// selectorSource 根据配置文件中的CSS选择器解析列表页
+type selectorSource struct {
+	cfg SourceConfig
+
+	// location 解析不带时区的日期时使用的时区
+	location *time.Location
+}
+
+func newSelectorSource(cfg SourceConfig) *selectorSource {
+	if cfg.Selectors.LinkAttr == "" {
+		cfg.Selectors.LinkAttr = "href"
+	}
+	return &selectorSource{cfg: cfg, location: cfg.location()}
+}
+
+func (s *selectorSource) Name() string {
+	return s.cfg.Name
+}
+
+func (s *selectorSource) Discover() []string {
+	return s.cfg.ListingURL
+}
+
+func (s *selectorSource) ParseListing(c *colly.Collector, out Emitter) {
+	sel := s.cfg.Selectors
+
+	c.OnHTML(sel.Item, func(e *colly.HTMLElement) {
+		if skipped(e.Response) {
+			return
+		}
+		out.Found(1)
+
+		defer func() {
+			if r := recover(); r != nil {
+				// 忽略解析错误
+			}
+		}()
+
+		title := firstText(e, sel.Title)
+		link := s.absolute(e, firstAttr(e, sel.Link, sel.LinkAttr))
+		summary := firstText(e, sel.Summary)
+		image := s.absolute(e, s.image(e))
+
+		if title == "" || link == "" {
+			return
+		}
+
+		article := newArticle(title, link, image, summary, s.cfg.Name)
+		article.ListingSnapshot = requestSnapshot(e.Request)
+		if published, ok := s.parseDate(e); ok {
+			article.PublishedAt = published
+		}
+		out.Emit(article)
+	})
+}
+
+func (s *selectorSource) ParseDetail(c *colly.Collector, emit func(content string)) {
+	parseSelectedDetail(c, s.cfg.Selectors.Detail, emit)
+}
+
+// parseSelectedDetail 使用配置的正文选择器解析详情页，未配置时使用通用规则
+func parseSelectedDetail(c *colly.Collector, selectors []string, emit func(content string)) {
+	if len(selectors) == 0 {
+		parseDefaultDetail(c, emit)
+		return
+	}
+
+	var found bool
+	c.OnHTML(strings.Join(selectors, ", "), func(e *colly.HTMLElement) {
+		if !found {
+			found = true
+			emit(cleanContent(e.DOM))
+		}
+	})
+}
+
+// absolute 补全相对地址和 // 开头的地址，配置了 url_prefix 时以其为基准，否则以列表页地址为基准
+func (s *selectorSource) absolute(e *colly.HTMLElement, link string) string {
+	base := s.cfg.URLPrefix
+	if base == "" {
+		base = e.Request.URL.String()
+	}
+	return resolveURL(base, link)
+}
+
+// image 返回条目的图片地址
+//
+// 配置了 image_attr 时直接读取该属性；否则自动识别 srcset、懒加载属性和占位图。
+func (s *selectorSource) image(e *colly.HTMLElement) string {
+	sel := s.cfg.Selectors
+	if sel.ImageAttr != "" {
+		return firstAttr(e, sel.Image, sel.ImageAttr)
+	}
+
+	for _, selector := range sel.Image {
+		if src := imageSource(e.DOM.Find(selector)); src != "" {
+			return src
+		}
+	}
+	return ""
+}
+
+// parseDate 按配置的选择器和格式解析列表页中的发布时间
+func (s *selectorSource) parseDate(e *colly.HTMLElement) (time.Time, bool) {
+	sel := s.cfg.Selectors
+	if len(sel.Date) == 0 {
+		return time.Time{}, false
+	}
+
+	var value string
+	if sel.DateAttr != "" {
+		value = firstAttr(e, sel.Date, sel.DateAttr)
+	} else {
+		value = firstText(e, sel.Date)
+	}
+
+	if sel.DateLayout != "" {
+		t, err := time.ParseInLocation(sel.DateLayout, strings.TrimSpace(value), s.location)
+		return t, err == nil
+	}
+	return parseLocalDate(value, s.location, time.Now())
+}
+
+// firstText 返回第一个有文本的选择器结果
+func firstText(e *colly.HTMLElement, selectors []string) string {
+	for _, selector := range selectors {
+		if text := strings.TrimSpace(e.ChildText(selector)); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// firstAttr 返回第一个有该属性的选择器结果
+func firstAttr(e *colly.HTMLElement, selectors []string, attr string) string {
+	for _, selector := range selectors {
+		if value := strings.TrimSpace(e.ChildAttr(selector, attr)); value != "" {
+			return value
+		}
+	}
+	return ""
+}
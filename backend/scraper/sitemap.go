@@ -0,0 +1,200 @@
+package scraper
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"golang.org/x/net/html/charset"
+)
+
+// DefaultSitemapMaxAge 是 sitemap 来源没有上次运行记录时回溯的时长
+const DefaultSitemapMaxAge = 48 * time.Hour
+
+// sitemapOverlap 是增量抓取时在上次运行时间之前多回溯的时长，
+// 用于容忍站点 lastmod 写入延迟和时钟偏差，重复的文章会在入库时去重
+const sitemapOverlap = time.Hour
+
+// maxSitemapBytes 限制解压后的 sitemap 大小，协议规定单个文件不超过 50MB
+const maxSitemapBytes = 50 << 20
+
+// SitemapSource 通过 sitemap.xml 和新闻 sitemap 发现文章
+//
+// 列表页只展示最新的一页，sitemap 则列出站点的全部文章。支持 sitemap 索引，
+// 按 lastmod 只访问上次运行以来有更新的子 sitemap，只提交此后发布或更新的文章。
+type SitemapSource struct {
+	name        string
+	sitemapURLs []string
+	pattern     *regexp.Regexp
+	maxAge      time.Duration
+	detail      []string
+
+	// location 解析不带时区的日期时使用的时区
+	location *time.Location
+}
+
+// newSitemapSource 根据配置创建 sitemap 来源
+func newSitemapSource(cfg SourceConfig) *SitemapSource {
+	maxAge := time.Duration(cfg.MaxAge)
+	if maxAge <= 0 {
+		maxAge = DefaultSitemapMaxAge
+	}
+
+	// url_pattern 已由 Validate 检查
+	var pattern *regexp.Regexp
+	if cfg.URLPattern != "" {
+		pattern, _ = regexp.Compile(cfg.URLPattern)
+	}
+
+	return &SitemapSource{
+		name:        cfg.Name,
+		sitemapURLs: cfg.SitemapURL,
+		pattern:     pattern,
+		maxAge:      maxAge,
+		detail:      cfg.Selectors.Detail,
+		location:    cfg.location(),
+	}
+}
+
+func (s *SitemapSource) Name() string {
+	return s.name
+}
+
+func (s *SitemapSource) Discover() []string {
+	return s.sitemapURLs
+}
+
+func (s *SitemapSource) ParseListing(c *colly.Collector, out Emitter) {
+	s.ParseListingSince(c, out, time.Time{})
+}
+
+// ParseListingSince 解析 sitemap 索引和 sitemap，只提交 since 之后发布或更新的文章，
+// since 为零值时回溯 max_age
+//
+// 没有日期的条目无法判断新旧，会被跳过。
+func (s *SitemapSource) ParseListingSince(c *colly.Collector, out Emitter, since time.Time) {
+	if since.IsZero() {
+		since = time.Now().Add(-s.maxAge)
+	} else {
+		since = since.Add(-sitemapOverlap)
+	}
+
+	c.OnResponse(func(r *colly.Response) {
+		doc, err := parseSitemap(r.Body)
+		if err != nil {
+			out.Error(fmt.Errorf("%s: parse sitemap: %w", r.Request.URL, err))
+			return
+		}
+
+		// sitemap 索引：只访问有更新的子 sitemap。子 sitemap 也计入匹配到的条目，
+		// 这样没有新文章时仍能看出 sitemap 本身是正常的
+		found := len(doc.Sitemaps)
+		for _, child := range doc.Sitemaps {
+			loc := strings.TrimSpace(child.Loc)
+			if loc == "" {
+				continue
+			}
+			if modified, ok := parseDate(child.LastMod, s.location); ok && modified.Before(since) {
+				continue
+			}
+			if err := r.Request.Visit(loc); err != nil && !errors.Is(err, colly.ErrAlreadyVisited) {
+				out.Error(fmt.Errorf("%s: %w", loc, err))
+			}
+		}
+
+		for _, entry := range doc.URLs {
+			link := strings.TrimSpace(entry.Loc)
+			if link == "" {
+				continue
+			}
+			link = r.Request.AbsoluteURL(link)
+			if s.pattern != nil && !s.pattern.MatchString(link) {
+				continue
+			}
+			found++
+
+			published, hasPublished := parseDate(entry.News.PublicationDate, s.location)
+			updated, hasUpdated := parseDate(entry.LastMod, s.location)
+			if !hasUpdated {
+				updated, hasUpdated = published, hasPublished
+			}
+			if !hasUpdated || updated.Before(since) {
+				continue
+			}
+
+			// 普通 sitemap 没有标题和图片，由详情页元信息补齐
+			var image string
+			if len(entry.Images) > 0 {
+				image = r.Request.AbsoluteURL(strings.TrimSpace(entry.Images[0].Loc))
+			}
+			article := newArticle(entry.News.Title, link, image, "", s.name)
+			article.ListingSnapshot = requestSnapshot(r.Request)
+			if hasPublished {
+				article.PublishedAt = published
+			}
+			out.Emit(article)
+		}
+		out.Found(found)
+	})
+}
+
+func (s *SitemapSource) ParseDetail(c *colly.Collector, emit func(content string)) {
+	parseSelectedDetail(c, s.detail, emit)
+}
+
+// sitemapDocument 同时兼容 sitemap 索引（<sitemapindex>）和 sitemap（<urlset>），
+// 字段按本地名匹配，忽略命名空间
+type sitemapDocument struct {
+	Sitemaps []sitemapRef   `xml:"sitemap"`
+	URLs     []sitemapEntry `xml:"url"`
+}
+
+type sitemapRef struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// sitemapEntry 是 sitemap 中的一个地址，包含新闻 sitemap 与图片 sitemap 的扩展字段
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+	News    struct {
+		Title           string `xml:"title"`
+		PublicationDate string `xml:"publication_date"`
+	} `xml:"news"`
+	Images []struct {
+		Loc string `xml:"loc"`
+	} `xml:"image"`
+}
+
+// parseSitemap 解析 sitemap 或 sitemap 索引，支持 gzip 压缩的 .xml.gz
+func parseSitemap(body []byte) (*sitemapDocument, error) {
+	if len(body) >= 2 && body[0] == 0x1f && body[1] == 0x8b {
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+
+		body, err = io.ReadAll(io.LimitReader(reader, maxSitemapBytes))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var doc sitemapDocument
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = charset.NewReaderLabel
+	decoder.Strict = false
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
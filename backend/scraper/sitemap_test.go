@@ -0,0 +1,220 @@
+package scraper
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+const newsSitemap = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"
+	xmlns:news="http://www.google.com/schemas/sitemap-news/0.9"
+	xmlns:image="http://www.google.com/schemas/sitemap-image/1.1">
+	<url>
+		<loc>https://example.com/news/zelda</loc>
+		<lastmod>2024-03-05T12:00:00Z</lastmod>
+		<news:news>
+			<news:publication>
+				<news:name>Example</news:name>
+				<news:language>en</news:language>
+			</news:publication>
+			<news:publication_date>2024-03-05T10:30:00Z</news:publication_date>
+			<news:title>Zelda sequel announced</news:title>
+		</news:news>
+		<image:image><image:loc>https://example.com/zelda.jpg</image:loc></image:image>
+	</url>
+	<url>
+		<loc> https://example.com/about </loc>
+	</url>
+</urlset>`
+
+func TestParseSitemap(t *testing.T) {
+	var gzipped bytes.Buffer
+	w := gzip.NewWriter(&gzipped)
+	w.Write([]byte(newsSitemap))
+	w.Close()
+
+	tests := []struct {
+		name     string
+		body     []byte
+		sitemaps []sitemapRef
+		locs     []string
+	}{
+		{
+			name: "sitemap index",
+			body: []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<sitemap><loc>https://example.com/sitemap-1.xml</loc><lastmod>2024-03-01</lastmod></sitemap>
+	<sitemap><loc>https://example.com/sitemap-2.xml.gz</loc></sitemap>
+</sitemapindex>`),
+			sitemaps: []sitemapRef{
+				{Loc: "https://example.com/sitemap-1.xml", LastMod: "2024-03-01"},
+				{Loc: "https://example.com/sitemap-2.xml.gz"},
+			},
+		},
+		{
+			name: "news sitemap",
+			body: []byte(newsSitemap),
+			locs: []string{"https://example.com/news/zelda", " https://example.com/about "},
+		},
+		{
+			name: "gzipped",
+			body: gzipped.Bytes(),
+			locs: []string{"https://example.com/news/zelda", " https://example.com/about "},
+		},
+		{
+			name: "empty urlset",
+			body: []byte(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"></urlset>`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseSitemap(tt.body)
+			if err != nil {
+				t.Fatalf("parseSitemap() error = %v", err)
+			}
+			if len(doc.Sitemaps) != len(tt.sitemaps) || (len(tt.sitemaps) > 0 && !reflect.DeepEqual(doc.Sitemaps, tt.sitemaps)) {
+				t.Errorf("sitemaps = %+v, want %+v", doc.Sitemaps, tt.sitemaps)
+			}
+			locs := make([]string, len(doc.URLs))
+			for i, entry := range doc.URLs {
+				locs[i] = entry.Loc
+			}
+			if len(locs) != len(tt.locs) || (len(tt.locs) > 0 && !reflect.DeepEqual(locs, tt.locs)) {
+				t.Errorf("urls = %q, want %q", locs, tt.locs)
+			}
+		})
+	}
+
+	doc, err := parseSitemap([]byte(newsSitemap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := doc.URLs[0]
+	if entry.News.Title != "Zelda sequel announced" || entry.News.PublicationDate != "2024-03-05T10:30:00Z" ||
+		len(entry.Images) != 1 || entry.Images[0].Loc != "https://example.com/zelda.jpg" {
+		t.Errorf("news entry = %+v", entry)
+	}
+}
+
+func TestParseSitemapInvalid(t *testing.T) {
+	for _, body := range []string{"not xml", "\x1f\x8bnot gzip"} {
+		if _, err := parseSitemap([]byte(body)); err == nil {
+			t.Errorf("parseSitemap(%q) error = nil, want an error", body)
+		}
+	}
+}
+
+// recordingEmitter 记录来源提交的文章和错误
+type recordingEmitter struct {
+	mu       sync.Mutex
+	found    int
+	articles []Article
+	errors   []error
+}
+
+func (e *recordingEmitter) Found(n int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.found += n
+}
+
+func (e *recordingEmitter) Emit(article Article) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.articles = append(e.articles, article)
+}
+
+func (e *recordingEmitter) Error(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.errors = append(e.errors, err)
+}
+
+func TestSitemapParseListingSince(t *testing.T) {
+	pages := map[string]string{
+		"/sitemap_index.xml": `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<sitemap><loc>/sitemap-new.xml</loc><lastmod>2024-03-05T00:00:00Z</lastmod></sitemap>
+	<sitemap><loc>/sitemap-undated.xml</loc></sitemap>
+	<sitemap><loc>/sitemap-old.xml</loc><lastmod>2023-01-01T00:00:00Z</lastmod></sitemap>
+</sitemapindex>`,
+		"/sitemap-new.xml": `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"
+	xmlns:news="http://www.google.com/schemas/sitemap-news/0.9">
+	<url>
+		<loc>/news/fresh</loc>
+		<news:news><news:publication_date>2024-03-05T10:00:00Z</news:publication_date><news:title>Fresh</news:title></news:news>
+	</url>
+	<url><loc>/news/updated</loc><lastmod>2024-03-04T23:30:00Z</lastmod></url>
+	<url><loc>/news/stale</loc><lastmod>2024-03-01T00:00:00Z</lastmod></url>
+	<url><loc>/news/undated</loc></url>
+	<url><loc>/tags/zelda</loc><lastmod>2024-03-05T10:00:00Z</lastmod></url>
+</urlset>`,
+		"/sitemap-undated.xml": `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>/news/other</loc><lastmod>2024-03-05T11:00:00+08:00</lastmod></url>
+</urlset>`,
+		"/sitemap-old.xml": `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>/news/ancient</loc><lastmod>2024-03-05T00:00:00Z</lastmod></url>
+</urlset>`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	source := newSitemapSource(SourceConfig{
+		Name:       "Example",
+		SitemapURL: []string{server.URL + "/sitemap_index.xml"},
+		URLPattern: "/news/",
+	})
+	out := &recordingEmitter{}
+	c := colly.NewCollector()
+	source.ParseListingSince(c, out, time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC))
+	if err := c.Visit(server.URL + "/sitemap_index.xml"); err != nil {
+		t.Fatal(err)
+	}
+	c.Wait()
+
+	if len(out.errors) > 0 {
+		t.Errorf("errors = %v", out.errors)
+	}
+
+	// 上次运行前一小时内的更新也会提交，见 sitemapOverlap
+	want := []string{"/news/fresh", "/news/other", "/news/updated"}
+	got := make([]string, len(out.articles))
+	for i, article := range out.articles {
+		got[i] = strings.TrimPrefix(article.URL, server.URL)
+	}
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("articles = %q, want %q", got, want)
+	}
+
+	// 索引中的三个子 sitemap，加上访问过的两个子 sitemap 中匹配 url_pattern 的五个地址
+	if out.found != 3+5 {
+		t.Errorf("found = %d, want %d", out.found, 3+5)
+	}
+
+	for _, article := range out.articles {
+		if strings.HasSuffix(article.URL, "/news/fresh") {
+			if article.Title != "Fresh" || !article.PublishedAt.Equal(time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)) {
+				t.Errorf("news entry = %+v", article)
+			}
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package scraper
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// Source 定义一个新闻来源站点的抓取方式
+//
+// 新增站点时只需在单独的文件中实现该接口，并在 init 中调用 Register 注册，
+// ScrapeGames 会遍历所有已启用的来源，无需修改核心抓取流程。
+type Source interface {
+	// Name 返回来源名称，会写入 Article.Source
+	Name() string
+
+	// Discover 返回需要访问的列表页URL
+	Discover() []string
+
+	// ParseListing 在列表页收集器上注册解析回调，匹配到条目时调用 out.Found，
+	// 每解析出一篇文章调用一次 out.Emit
+	ParseListing(c *colly.Collector, out Emitter)
+
+	// ParseDetail 在详情页收集器上注册解析回调，解析出正文后调用 emit
+	ParseDetail(c *colly.Collector, emit func(content string))
+}
+
+// IncrementalSource 是能只抓取某个时间之后发布的文章的来源，例如 sitemap
+//
+// ScrapeGames 会传入该来源上一次文章成功入库的抓取时间，代替 ParseListing 调用。
+type IncrementalSource interface {
+	Source
+
+	// ParseListingSince 与 ParseListing 相同，但只提交 since 之后发布或更新的文章，
+	// since 为零值表示没有上次运行的记录
+	ParseListingSince(c *colly.Collector, out Emitter, since time.Time)
+}
+
+// Registry 保存已注册的来源及其启用状态
+type Registry struct {
+	mu       sync.RWMutex
+	sources  []Source
+	disabled map[string]bool
+}
+
+// NewRegistry 创建一个空的来源注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		sources:  make([]Source, 0),
+		disabled: make(map[string]bool),
+	}
+}
+
+// defaultRegistry 是各来源文件在 init 中注册的默认注册表
+var defaultRegistry = NewRegistry()
+
+// Register 将来源注册到默认注册表，通常在来源文件的 init 中调用
+func Register(src Source) {
+	defaultRegistry.Register(src)
+}
+
+// Register 注册一个来源，同名来源会被替换
+func (r *Registry) Register(src Source) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, existing := range r.sources {
+		if existing.Name() == src.Name() {
+			r.sources[i] = src
+			return
+		}
+	}
+	r.sources = append(r.sources, src)
+}
+
+// Enable 启用指定名称的来源
+func (r *Registry) Enable(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.disabled, name)
+}
+
+// Disable 禁用指定名称的来源，禁用后 ScrapeGames 不再抓取它
+func (r *Registry) Disable(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.disabled[name] = true
+}
+
+// Lookup 按名称查找来源
+func (r *Registry) Lookup(name string) (Source, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, src := range r.sources {
+		if src.Name() == name {
+			return src, true
+		}
+	}
+	return nil, false
+}
+
+// Enabled 按注册顺序返回所有已启用的来源
+func (r *Registry) Enabled() []Source {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sources := make([]Source, 0, len(r.sources))
+	for _, src := range r.sources {
+		if !r.disabled[src.Name()] {
+			sources = append(sources, src)
+		}
+	}
+	return sources
+}
+
+// ForURL 根据URL的域名找到对应的来源，用于详情页解析
+func (r *Registry) ForURL(rawURL string) (Source, bool) {
+	host := hostOf(rawURL)
+	if host == "" {
+		return nil, false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, src := range r.sources {
+		for _, listing := range src.Discover() {
+			if hostOf(listing) == host {
+				return src, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// clone 复制注册表，使每个 Scraper 的启用状态互不影响
+func (r *Registry) clone() *Registry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c := NewRegistry()
+	c.sources = append(c.sources, r.sources...)
+	for name := range r.disabled {
+		c.disabled[name] = true
+	}
+	return c
+}
+
+// hostOf 返回URL去掉 www. 前缀后的小写域名
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"game-news/scraper"
+)
+
+// backfillListing renders a listing page with one item per slug and date
+func backfillListing(items ...string) string {
+	var b strings.Builder
+	b.WriteString("<html><body>")
+	for i := 0; i+1 < len(items); i += 2 {
+		fmt.Fprintf(&b, `<div class="item"><a href="/news/%s">Story %s</a><time>%s</time></div>`, items[i], items[i], items[i+1])
+	}
+	b.WriteString("</body></html>")
+	return b.String()
+}
+
+func TestBackfill(t *testing.T) {
+	t.Setenv("MONGO_URI", "")
+	s := NewStorage()
+
+	pages := map[string]string{
+		// A pinned post from years ago does not end the backfill on the first page
+		"/news":        backfillListing("pinned", "2022-01-01", "jan-11", "2024-01-11", "jan-10", "2024-01-10"),
+		"/news/page/2": backfillListing("dec-12", "2023-12-12", "dec-11", "2023-12-11", "dec-10", "2023-12-10"),
+		"/news/page/3": backfillListing("nov-12", "2023-11-12", "nov-11", "2023-11-11", "nov-10", "2023-11-10"),
+	}
+	for _, slug := range []string{"pinned", "jan-11", "jan-10", "dec-12", "dec-11", "dec-10", "nov-12", "nov-11", "nov-10"} {
+		pages["/news/"+slug] = zeldaPage
+	}
+	site, server := newArticleSite(t, pages)
+
+	scr := scraper.NewScraperWithConfig(&scraper.Config{
+		Politeness: scraper.PolitenessConfig{Default: scraper.DomainPolicy{Parallelism: 4}},
+		Sources: []scraper.SourceConfig{{
+			Name:       "Example",
+			ListingURL: scraper.StringList{server.URL + "/news"},
+			Selectors: scraper.SelectorConfig{
+				Item:       "div.item",
+				Title:      scraper.StringList{"a"},
+				Link:       scraper.StringList{"a"},
+				Date:       scraper.StringList{"time"},
+				DateLayout: "2006-01-02",
+			},
+			Pagination: scraper.PaginationConfig{PageURL: server.URL + "/news/page/{page}"},
+		}},
+	})
+	day := func(value string) time.Time {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	// The first call stops at maxPages and saves where it got to
+	state, err := s.Backfill(context.Background(), scr, "Example", day("2023-12-15"), 1)
+	if err != nil {
+		t.Fatalf("Backfill() error = %v", err)
+	}
+	if state.Done() || state.Pages != 1 || state.Articles != 3 || state.Page != 2 ||
+		state.NextURL != server.URL+"/news/page/2" || !state.Oldest.Equal(day("2024-01-10")) {
+		t.Fatalf("state after one page = %+v", state)
+	}
+	if saved, found, err := s.GetBackfill("Example"); err != nil || !found || saved.NextURL != state.NextURL {
+		t.Fatalf("GetBackfill() = %+v, %v, %v", saved, found, err)
+	}
+	if _, ok := s.inMemoryArticles[scraper.ArticleID(server.URL+"/news/jan-10")]; !ok {
+		t.Error("articles of the first page were not stored")
+	}
+
+	// A cancelled call records the error and keeps the position
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.Backfill(ctx, scr, "Example", day("2023-12-15"), 0); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled Backfill() error = %v", err)
+	}
+	saved, _, _ := s.GetBackfill("Example")
+	if saved.LastError == "" || saved.Page != 2 || saved.Pages != 1 {
+		t.Errorf("state after a cancelled call = %+v", saved)
+	}
+
+	// Resuming starts from the saved page and stops once the median date of
+	// a page reaches the target
+	state, err = s.Backfill(context.Background(), scr, "Example", day("2023-12-15"), 0)
+	if err != nil {
+		t.Fatalf("resumed Backfill() error = %v", err)
+	}
+	if !state.Done() || state.Exhausted || state.Pages != 2 || state.LastError != "" || !state.Oldest.Equal(day("2023-12-11")) {
+		t.Errorf("state after resuming = %+v", state)
+	}
+	if n := site.requestCount("/news"); n != 1 {
+		t.Errorf("first page crawled %d times, want 1", n)
+	}
+	if n := site.requestCount("/news/page/3"); n != 0 {
+		t.Errorf("page past the target date crawled %d times", n)
+	}
+
+	// An earlier date carries on from where the backfill stopped
+	state, err = s.Backfill(context.Background(), scr, "Example", day("2023-11-15"), 0)
+	if err != nil {
+		t.Fatalf("Backfill() to an earlier date error = %v", err)
+	}
+	if !state.Done() || state.Pages != 3 || state.Articles != 9 || !state.Oldest.Equal(day("2023-11-11")) {
+		t.Errorf("state after an earlier date = %+v", state)
+	}
+
+	// Running past the last page marks the source exhausted
+	state, err = s.Backfill(context.Background(), scr, "Example", day("2020-01-01"), 0)
+	if err != nil {
+		t.Fatalf("Backfill() past the last page error = %v", err)
+	}
+	if !state.Done() || !state.Exhausted || state.Pages != 4 || state.Articles != 9 {
+		t.Errorf("state past the last page = %+v", state)
+	}
+	if n := site.requestCount("/news/page/2"); n != 1 {
+		t.Errorf("second page crawled %d times, want 1", n)
+	}
+
+	if err := s.DeleteBackfill("Example"); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, _ := s.GetBackfill("Example"); found {
+		t.Error("DeleteBackfill() kept the progress")
+	}
+}
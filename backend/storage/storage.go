@@ -2,12 +2,17 @@ package storage
 
 import (
 	"context"
+	"crypto/md5"
+	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 	"game-news/scraper"
+	"game-news/textutil"
 	"log"
 	"os"
+	"sort"
 	
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -16,14 +21,54 @@ import (
 
 // ArticleWithContent 扩展文章结构以包含详细内容
 type ArticleWithContent struct {
-	ID          string    `bson:"id"`
-	Title       string    `bson:"title"`
-	URL         string    `bson:"url"`
-	ImageURL    string    `bson:"image_url"`
-	Summary     string    `bson:"summary"`
-	Source      string    `bson:"source"`
-	PublishedAt time.Time `bson:"published_at"`
-	Content     string    `bson:"content"`
+	ID           string    `bson:"id"`
+	Title        string    `bson:"title"`
+	URL          string    `bson:"url"`
+	CanonicalURL string    `bson:"canonical_url"`
+	ImageURL     string    `bson:"image_url"`
+	Summary      string    `bson:"summary"`
+	Source       string    `bson:"source"`
+	Author       string    `bson:"author"`
+	PublishedAt  time.Time `bson:"published_at"`
+	ModifiedAt   time.Time `bson:"modified_at"`
+	Content      string    `bson:"content"`
+	
+	// FirstSeenAt and LastSeenAt record when the article first and most
+	// recently appeared in a source listing
+	FirstSeenAt time.Time `bson:"first_seen_at"`
+	LastSeenAt  time.Time `bson:"last_seen_at"`
+	
+	// ListingHash identifies the listing fields the article was last scraped
+	// with, so unchanged items can skip the detail page fetch
+	ListingHash string `bson:"listing_hash"`
+	
+	// Snapshot is the archive key of the raw detail page HTML the content was
+	// extracted from, empty when archiving is disabled
+	Snapshot string `bson:"snapshot,omitempty"`
+	
+	// ListingSnapshot is the archive key of the listing page (or feed or
+	// sitemap) the listing fields were extracted from
+	ListingSnapshot string `bson:"listing_snapshot,omitempty"`
+	
+	// StoryID is the story cluster the article belongs to, empty while no
+	// related coverage has been found
+	StoryID string `bson:"story_id,omitempty"`
+	
+	// Aliases are other IDs that resolve to this article: legacy 8-character
+	// IDs and IDs of listing links that differ from the canonical URL
+	Aliases []string `bson:"aliases,omitempty"`
+	
+	// Fingerprint is the SimHash of the title and content (stored as int64
+	// bits), zero when the text is too short to fingerprint
+	Fingerprint int64 `bson:"fingerprint"`
+	
+	// DuplicateOf is the ID of the earliest article from another source that
+	// this one is a near-duplicate of, empty for original coverage
+	DuplicateOf string `bson:"duplicate_of,omitempty"`
+	
+	// Language is the ISO 639-1 code detected from the title, summary and
+	// content (see textutil.DetectLanguage), empty when it could not be told
+	Language string `bson:"language"`
 }
 
 // User represents a user in the system
@@ -42,19 +87,71 @@ type Bookmark struct {
 	CreatedAt time.Time `bson:"created_at"`
 }
 
+// ScrapeRun is the persisted report of one scrape run for a single source
+type ScrapeRun struct {
+	RunID       string         `bson:"run_id"`
+	Source      string         `bson:"source"`
+	StartedAt   time.Time      `bson:"started_at"`
+	DurationMs  int64          `bson:"duration_ms"`
+	URLsVisited []string       `bson:"urls_visited"`
+	Statuses    map[string]int `bson:"statuses"`
+	ItemsFound  int            `bson:"items_found"`
+	ItemsParsed int            `bson:"items_parsed"`
+	FieldCounts map[string]int `bson:"field_counts"`
+	Incremental bool           `bson:"incremental"`
+	Unchanged   int            `bson:"unchanged"`
+	Errors      []string       `bson:"errors"`
+	Snapshots   []string       `bson:"snapshots,omitempty"`
+	Stored      bool           `bson:"stored"`
+}
+
+// SourceEvent is a persisted "source degraded" event raised by drift detection
+type SourceEvent struct {
+	Type       string    `bson:"type"`
+	Source     string    `bson:"source"`
+	RunID      string    `bson:"run_id"`
+	DetectedAt time.Time `bson:"detected_at"`
+	Reasons    []string  `bson:"reasons"`
+}
+
+// SourceEventDegraded is the type of events raised when a source's yield drops
+const SourceEventDegraded = "source_degraded"
+
+// maxScrapeRunsPerSource caps the run history kept per source
+const maxScrapeRunsPerSource = 100
+
+// maxSourceEvents caps the number of source events kept
+const maxSourceEvents = 500
+
+// duplicateWindow is how far apart two articles can be published and still
+// be considered coverage of the same story
+const duplicateWindow = 72 * time.Hour
+
+// legacyIDLength is the length of article IDs generated before URL
+// canonicalization (the first 8 hex characters of md5(link))
+const legacyIDLength = 8
+
 // Storage handles storage of news articles
 type Storage struct {
-	client    *mongo.Client
-	database  *mongo.Database
-	articles  *mongo.Collection
-	users     *mongo.Collection
-	bookmarks *mongo.Collection
-	mu        sync.RWMutex
+	client     *mongo.Client
+	database   *mongo.Database
+	articles   *mongo.Collection
+	users      *mongo.Collection
+	bookmarks  *mongo.Collection
+	scrapeRuns *mongo.Collection
+	sourceEvents *mongo.Collection
+	stories    *mongo.Collection
+	backfills  *mongo.Collection
+	mu         sync.RWMutex
 	
 	// In-memory storage for when no database is available
 	inMemoryArticles map[string]ArticleWithContent
 	inMemoryUsers    map[string]User
 	inMemoryBookmarks map[int64][]string
+	inMemoryScrapeRuns map[string][]ScrapeRun
+	inMemorySourceEvents []SourceEvent
+	inMemoryStories  map[string]Story
+	inMemoryBackfills map[string]BackfillState
 	useInMemory      bool
 }
 
@@ -65,6 +162,10 @@ func NewStorage() *Storage {
 		inMemoryArticles:  make(map[string]ArticleWithContent),
 		inMemoryUsers:     make(map[string]User),
 		inMemoryBookmarks: make(map[int64][]string),
+		inMemoryScrapeRuns: make(map[string][]ScrapeRun),
+		inMemorySourceEvents: make([]SourceEvent, 0),
+		inMemoryStories:   make(map[string]Story),
+		inMemoryBackfills: make(map[string]BackfillState),
 		useInMemory:       true,
 	}
 	
@@ -101,6 +202,10 @@ func NewStorage() *Storage {
 	storage.articles = database.Collection("articles")
 	storage.users = database.Collection("users")
 	storage.bookmarks = database.Collection("bookmarks")
+	storage.scrapeRuns = database.Collection("scrape_runs")
+	storage.sourceEvents = database.Collection("source_events")
+	storage.stories = database.Collection("stories")
+	storage.backfills = database.Collection("backfills")
 	storage.useInMemory = false
 	
 	// Create indexes
@@ -109,6 +214,12 @@ func NewStorage() *Storage {
 	return storage
 }
 
+// InMemory reports whether the storage keeps its data in memory, because
+// MONGO_URI is not set or MongoDB could not be reached
+func (s *Storage) InMemory() bool {
+	return s.useInMemory
+}
+
 // createIndexes creates necessary indexes for collections
 func (s *Storage) createIndexes() {
 	if s.useInMemory {
@@ -129,6 +240,29 @@ func (s *Storage) createIndexes() {
 		{
 			Keys: bson.D{{"source", 1}},
 		},
+		{
+			Keys: bson.D{{"aliases", 1}},
+		},
+		{
+			Keys: bson.D{{"duplicate_of", 1}},
+		},
+		{
+			Keys: bson.D{{"story_id", 1}},
+		},
+		{
+			Keys: bson.D{{"language", 1}, {"published_at", -1}},
+		},
+	})
+	
+	// Stories indexes
+	s.stories.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{"id", 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{"last_published_at", -1}},
+		},
 	})
 	
 	// Users indexes
@@ -152,103 +286,1078 @@ func (s *Storage) createIndexes() {
 			Keys: bson.D{{"article_id", 1}},
 		},
 	})
+	
+	// Scrape runs indexes
+	s.scrapeRuns.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{"source", 1}, {"started_at", -1}},
+		},
+	})
+	
+	// Backfills indexes
+	s.backfills.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{"source", 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	})
+	
+	// Source events indexes
+	s.sourceEvents.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{"detected_at", -1}},
+		},
+		{
+			Keys: bson.D{{"source", 1}, {"detected_at", -1}},
+		},
+	})
+}
+
+// newArticleWithContent converts a scraped article and its content into the stored form
+func newArticleWithContent(article scraper.Article, content string) ArticleWithContent {
+	// Fall back to the ingestion time when neither the listing nor the
+	// detail page metadata provided a publish date
+	now := time.Now()
+	publishedAt := article.PublishedAt
+	if publishedAt.IsZero() {
+		publishedAt = now
+	}
+	
+	return ArticleWithContent{
+		ID:           article.ID,
+		Title:        article.Title,
+		URL:          article.URL,
+		CanonicalURL: article.CanonicalURL,
+		ImageURL:     article.ImageURL,
+		Summary:      article.Summary,
+		Source:       article.Source,
+		Author:       article.Author,
+		PublishedAt:  publishedAt,
+		ModifiedAt:   article.ModifiedAt,
+		Content:      content,
+		FirstSeenAt:  now,
+		LastSeenAt:   now,
+		ListingHash:  listingHash(article),
+		
+		ListingSnapshot: article.ListingSnapshot,
+	}
+}
+
+// listingHash hashes the fields a source listing provides for an article
+func listingHash(article scraper.Article) string {
+	key := strings.Join([]string{
+		article.Title,
+		article.URL,
+		article.ImageURL,
+		article.Summary,
+		article.PublishedAt.UTC().Format(time.RFC3339),
+	}, "\x00")
+	return fmt.Sprintf("%x", md5.Sum([]byte(key)))
+}
+
+// AddArticle adds a new article to storage
+func (s *Storage) AddArticle(article scraper.Article, content string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	articleWithContent := newArticleWithContent(article, content)
+	
+	// If using in-memory storage
+	if s.useInMemory {
+		s.inMemoryArticles[article.ID] = articleWithContent
+		return nil
+	}
+	
+	// Use MongoDB
+	ctx := context.Background()
+	
+	_, err := s.articles.UpdateOne(
+		ctx,
+		bson.M{"id": article.ID},
+		bson.M{"$set": articleWithContent},
+		options.Update().SetUpsert(true),
+	)
+	
+	return err
+}
+
+// AddArticles adds multiple articles to storage
+//
+// Articles that are already stored with the same listing fields only have
+// their last_seen_at refreshed. New or changed articles have their detail page
+// scraped for content and structured metadata (JSON-LD, Open Graph and article
+// meta tags), which fill in the publish date, author, canonical URL and lead
+// image. Detail pages are fetched concurrently before any lock is taken, so
+// reads are never blocked by the network crawl.
+func (s *Storage) AddArticles(articles []scraper.Article, scraperInstance *scraper.Scraper) error {
+	return s.AddArticlesContext(context.Background(), articles, scraperInstance)
+}
+
+// AddArticlesContext is AddArticles with a context that cancels the detail
+// page crawl. A batch interrupted before it is written returns ctx.Err() and
+// stores nothing; once writing has started the batch is finished.
+func (s *Storage) AddArticlesContext(ctx context.Context, articles []scraper.Article, scraperInstance *scraper.Scraper) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	
+	ids := make([]string, len(articles))
+	for i, article := range articles {
+		ids[i] = article.ID
+	}
+	
+	existing, err := s.findArticlesByID(ids)
+	if err != nil {
+		return err
+	}
+	
+	// Split the listing into unchanged articles and ones that need their details fetched
+	unchanged := make([]string, 0)
+	toFetch := make([]scraper.Article, 0)
+	queued := make(map[string]bool)
+	for _, article := range articles {
+		if queued[article.ID] {
+			continue
+		}
+		queued[article.ID] = true
+		
+		if stored, ok := existing[article.ID]; ok && stored.ListingHash == listingHash(article) {
+			unchanged = append(unchanged, stored.ID)
+			continue
+		}
+		toFetch = append(toFetch, article)
+	}
+	
+	details, errs := scraperInstance.ScrapeDetailsContext(ctx, toFetch)
+	
+	// Articles whose details were never fetched would be stored without
+	// content, so drop the whole batch and let the next run pick it up
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	
+	articlesWithContent := make([]ArticleWithContent, 0, len(toFetch))
+	moved := make(map[string]string)
+	fetched := make([]string, 0, len(toFetch))
+	for i, article := range toFetch {
+		listed := article
+		article = article.WithMetadata(details[i].Metadata)
+		articleWithContent := newArticleWithContent(article, details[i].Content)
+		articleWithContent.ListingHash = listingHash(listed)
+		articleWithContent.Snapshot = details[i].Snapshot
+		
+		// Prefer the ID derived from rel=canonical and keep the listing ID as an
+		// alias, so later runs still find the article from its listing link
+		articleWithContent.ID = scraper.CanonicalArticleID(article)
+		articleWithContent.Aliases = mergeAliases(articleWithContent.ID, []string{listed.ID})
+		
+		stored, known := existing[listed.ID]
+		
+		// A failed detail fetch is stored without content and with an empty
+		// listing hash, so the next run retries it. Known articles keep their
+		// previous content and identity instead of losing them.
+		if errs[i] != nil {
+			log.Printf("Failed to scrape details of %s: %v", article.URL, errs[i])
+			// Incremental sources will not list it again, so the scraper
+			// resubmits it on its next runs
+			scraperInstance.RetryLater(listed)
+			articleWithContent.ListingHash = ""
+			if known {
+				articleWithContent.ID = stored.ID
+				articleWithContent.CanonicalURL = stored.CanonicalURL
+				articleWithContent.Content = stored.Content
+				articleWithContent.ModifiedAt = stored.ModifiedAt
+				articleWithContent.Snapshot = stored.Snapshot
+				articleWithContent.Aliases = mergeAliases(stored.ID, []string{listed.ID})
+			}
+		} else {
+			fetched = append(fetched, listed.ID)
+		}
+		
+		// An article that moved to its canonical ID takes the old document's
+		// aliases and bookmarks along, and the old document is removed
+		if known && stored.ID != articleWithContent.ID {
+			articleWithContent.Aliases = mergeAliases(articleWithContent.ID, articleWithContent.Aliases, stored.Aliases, []string{stored.ID})
+			moved[stored.ID] = articleWithContent.ID
+		}
+		
+		// Keep the original first-seen time and publish date of known articles
+		// so their ordering stays stable across runs
+		if known {
+			articleWithContent.StoryID = stored.StoryID
+			articleWithContent.FirstSeenAt = stored.FirstSeenAt
+			if articleWithContent.FirstSeenAt.IsZero() {
+				articleWithContent.FirstSeenAt = stored.PublishedAt
+			}
+			if article.PublishedAt.IsZero() {
+				articleWithContent.PublishedAt = stored.PublishedAt
+			}
+		}
+		
+		articleWithContent.Language = articleLanguage(articleWithContent)
+		
+		// Sitemap entries carry no title of their own; one whose detail page
+		// gave none either cannot be listed. If the fetch failed, the scraper
+		// resubmits it on later runs.
+		if articleWithContent.Title == "" {
+			log.Printf("Skipping %s: no title", article.URL)
+			continue
+		}
+		
+		articlesWithContent = append(articlesWithContent, articleWithContent)
+	}
+	
+	// Compare against recently published stored articles to find
+	// near-duplicates and related coverage of the same story
+	recent, err := s.articlesPublishedSince(earliestPublished(articlesWithContent).Add(-duplicateWindow))
+	if err != nil {
+		return err
+	}
+	markDuplicates(articlesWithContent, recent)
+	if err := s.assignStories(articlesWithContent, recent); err != nil {
+		return err
+	}
+	
+	if err := s.touchArticles(unchanged, time.Now()); err != nil {
+		return err
+	}
+	
+	if err := s.saveArticles(articlesWithContent); err != nil {
+		return err
+	}
+	
+	if err := s.moveArticles(moved); err != nil {
+		return err
+	}
+	
+	scraperInstance.Stored(fetched...)
+	return nil
+}
+
+// markDuplicates fingerprints the articles and points each near-duplicate at
+// the earliest seen matching article from another source published within
+// duplicateWindow, so listings can collapse the same story into one entry.
+// An article that other articles already point at stays the primary, and an
+// article is never marked as a duplicate of one first seen after it.
+func markDuplicates(articles []ArticleWithContent, recent []ArticleWithContent) {
+	primaries := make(map[string]bool)
+	for _, article := range recent {
+		if article.DuplicateOf != "" {
+			primaries[article.DuplicateOf] = true
+		}
+	}
+	
+	for i := range articles {
+		articles[i].Fingerprint = int64(textutil.SimHash(articles[i].Title + "\n" + fingerprintText(articles[i])))
+		articles[i].DuplicateOf = ""
+	}
+	
+	candidates := make([]ArticleWithContent, 0, len(recent))
+	for _, article := range recent {
+		if article.Fingerprint != 0 {
+			candidates = append(candidates, article)
+		}
+	}
+	
+	// Earlier coverage becomes the primary article
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return firstSeen(candidates[i]).Before(firstSeen(candidates[j]))
+	})
+	
+	for i := range articles {
+		article := &articles[i]
+		
+		for _, candidate := range candidates {
+			if primaries[article.ID] || firstSeen(candidate).After(firstSeen(*article)) {
+				break
+			}
+			if candidate.ID == article.ID || candidate.DuplicateOf == article.ID || candidate.Source == article.Source {
+				continue
+			}
+			
+			gap := candidate.PublishedAt.Sub(article.PublishedAt)
+			if gap > duplicateWindow || gap < -duplicateWindow {
+				continue
+			}
+			
+			if textutil.NearDuplicate(uint64(article.Fingerprint), uint64(candidate.Fingerprint)) {
+				article.DuplicateOf = candidate.ID
+				if candidate.DuplicateOf != "" {
+					article.DuplicateOf = candidate.DuplicateOf
+				}
+				primaries[article.DuplicateOf] = true
+				break
+			}
+		}
+		
+		if article.Fingerprint != 0 {
+			candidates = insertBySeen(candidates, *article)
+		}
+	}
+}
+
+// firstSeen returns when an article was first seen, falling back to its
+// publish date for articles stored before first-seen times were recorded
+func firstSeen(article ArticleWithContent) time.Time {
+	if article.FirstSeenAt.IsZero() {
+		return article.PublishedAt
+	}
+	return article.FirstSeenAt
+}
+
+// insertBySeen inserts an article into candidates sorted by first-seen time,
+// after any candidates seen at the same time, replacing its stored version
+func insertBySeen(candidates []ArticleWithContent, article ArticleWithContent) []ArticleWithContent {
+	for i, candidate := range candidates {
+		if candidate.ID == article.ID {
+			candidates = append(candidates[:i], candidates[i+1:]...)
+			break
+		}
+	}
+	
+	i := sort.Search(len(candidates), func(i int) bool {
+		return firstSeen(candidates[i]).After(firstSeen(article))
+	})
+	candidates = append(candidates, ArticleWithContent{})
+	copy(candidates[i+1:], candidates[i:])
+	candidates[i] = article
+	return candidates
+}
+
+// earliestPublished returns the earliest publish date among the articles
+func earliestPublished(articles []ArticleWithContent) time.Time {
+	earliest := time.Now()
+	for _, article := range articles {
+		if article.PublishedAt.Before(earliest) {
+			earliest = article.PublishedAt
+		}
+	}
+	return earliest
+}
+
+// fingerprintText is the body used for fingerprinting, falling back to the
+// summary when the detail page content is missing
+func fingerprintText(article ArticleWithContent) string {
+	if article.Content != "" {
+		return article.Content
+	}
+	return article.Summary
+}
+
+// articlesPublishedSince returns the articles published since the given time, without content
+func (s *Storage) articlesPublishedSince(since time.Time) ([]ArticleWithContent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	// If using in-memory storage
+	if s.useInMemory {
+		articles := make([]ArticleWithContent, 0)
+		for _, article := range s.inMemoryArticles {
+			if !article.PublishedAt.Before(since) {
+				article.Content = ""
+				articles = append(articles, article)
+			}
+		}
+		return articles, nil
+	}
+	
+	// Use MongoDB
+	ctx := context.Background()
+	
+	filter := bson.M{"published_at": bson.M{"$gte": since}}
+	cursor, err := s.articles.Find(ctx, filter, options.Find().SetProjection(bson.M{"content": 0}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	
+	articles := make([]ArticleWithContent, 0)
+	if err = cursor.All(ctx, &articles); err != nil {
+		return nil, err
+	}
+	
+	return articles, nil
+}
+
+// GetDuplicateGroups returns every stored article of the given stories, keyed
+// by story ID. A story ID is the ID of its primary article; the group holds the
+// primary and all articles marked as its near-duplicates. Content is omitted.
+func (s *Storage) GetDuplicateGroups(storyIDs []string) (map[string][]ArticleWithContent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	groups := make(map[string][]ArticleWithContent)
+	if len(storyIDs) == 0 {
+		return groups, nil
+	}
+	
+	wanted := make(map[string]bool, len(storyIDs))
+	for _, id := range storyIDs {
+		wanted[id] = true
+	}
+	
+	var articles []ArticleWithContent
+	
+	// If using in-memory storage
+	if s.useInMemory {
+		for _, article := range s.inMemoryArticles {
+			if wanted[article.ID] || wanted[article.DuplicateOf] {
+				article.Content = ""
+				articles = append(articles, article)
+			}
+		}
+	} else {
+		// Use MongoDB
+		ctx := context.Background()
+		
+		filter := bson.M{"$or": bson.A{
+			bson.M{"id": bson.M{"$in": storyIDs}},
+			bson.M{"duplicate_of": bson.M{"$in": storyIDs}},
+		}}
+		findOptions := options.Find().SetProjection(bson.M{"content": 0})
+		cursor, err := s.articles.Find(ctx, filter, findOptions)
+		if err != nil {
+			return nil, err
+		}
+		defer cursor.Close(ctx)
+		
+		if err = cursor.All(ctx, &articles); err != nil {
+			return nil, err
+		}
+	}
+	
+	for _, article := range articles {
+		storyID := article.ID
+		if article.DuplicateOf != "" {
+			storyID = article.DuplicateOf
+		}
+		groups[storyID] = append(groups[storyID], article)
+	}
+	
+	// Oldest coverage first
+	for _, group := range groups {
+		sort.Slice(group, func(i, j int) bool {
+			return group[i].PublishedAt.Before(group[j].PublishedAt)
+		})
+	}
+	
+	return groups, nil
+}
+
+// mergeAliases returns the union of the alias lists in order, without
+// duplicates and without the article's own ID
+func mergeAliases(id string, lists ...[]string) []string {
+	seen := map[string]bool{id: true}
+	merged := make([]string, 0)
+	for _, list := range lists {
+		for _, alias := range list {
+			if alias == "" || seen[alias] {
+				continue
+			}
+			seen[alias] = true
+			merged = append(merged, alias)
+		}
+	}
+	return merged
+}
+
+// ReparseArticles re-runs listing extraction over the archived listing page
+// snapshots and content and metadata extraction over the archived detail
+// page snapshots of stored articles, without fetching the sites again.
+// An empty source reparses every source. It returns the number of articles updated.
+func (s *Storage) ReparseArticles(scraperInstance *scraper.Scraper, source string) (int, error) {
+	articles, err := s.GetArticles()
+	if err != nil {
+		return 0, err
+	}
+	
+	// A listing snapshot holds many articles, so each one is parsed only once
+	listings := make(map[string]map[string]scraper.Article)
+	listing := func(stored ArticleWithContent) (map[string]scraper.Article, error) {
+		if listed, ok := listings[stored.ListingSnapshot]; ok {
+			return listed, nil
+		}
+		reparsed, err := scraperInstance.ReparseListingSnapshot(stored.Source, stored.ListingSnapshot)
+		listed := make(map[string]scraper.Article, len(reparsed))
+		for _, article := range reparsed {
+			listed[article.ID] = article
+		}
+		listings[stored.ListingSnapshot] = listed
+		return listed, err
+	}
+	
+	updated := make([]ArticleWithContent, 0)
+	for _, stored := range articles {
+		if source != "" && stored.Source != source {
+			continue
+		}
+		changed := false
+		
+		if stored.ListingSnapshot != "" {
+			listed, err := listing(stored)
+			if err != nil {
+				log.Printf("Failed to reparse listing of %s: %v", stored.ID, err)
+			}
+			if article, ok := listedArticle(stored, listed); ok {
+				stored = relistedArticle(stored, article)
+				changed = true
+			}
+		}
+		
+		if stored.Snapshot != "" {
+			details, err := scraperInstance.ReparseSnapshot(stored.URL, stored.Snapshot)
+			if err != nil {
+				log.Printf("Failed to reparse %s: %v", stored.ID, err)
+			} else {
+				stored = reparsedArticle(stored, details)
+				changed = true
+			}
+		}
+		
+		if changed {
+			updated = append(updated, stored)
+		}
+	}
+	
+	return len(updated), s.saveArticles(updated)
+}
+
+// listedArticle finds a stored article among reparsed listing items, which
+// are keyed by the ID of their listing link
+func listedArticle(stored ArticleWithContent, listed map[string]scraper.Article) (scraper.Article, bool) {
+	for _, id := range append([]string{stored.ID}, stored.Aliases...) {
+		if article, ok := listed[id]; ok {
+			return article, true
+		}
+	}
+	return scraper.Article{}, false
+}
+
+// relistedArticle applies freshly extracted listing fields to a stored
+// article. Fields the listing no longer yields keep their stored values, and
+// the listing hash is updated so the next run compares against the new fields.
+func relistedArticle(stored ArticleWithContent, listed scraper.Article) ArticleWithContent {
+	if listed.Title != "" {
+		stored.Title = listed.Title
+	}
+	if listed.ImageURL != "" {
+		stored.ImageURL = listed.ImageURL
+	}
+	if listed.Summary != "" {
+		stored.Summary = listed.Summary
+	}
+	if listed.Author != "" {
+		stored.Author = listed.Author
+	}
+	if !listed.PublishedAt.IsZero() {
+		stored.PublishedAt = listed.PublishedAt
+	}
+	stored.ListingHash = listingHash(listed)
+	stored.Language = articleLanguage(stored)
+	
+	return stored
+}
+
+// SnapshotKeys returns the archive keys of every detail and listing page
+// snapshot still referenced by a stored article
+func (s *Storage) SnapshotKeys() (map[string]bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	keys := make(map[string]bool)
+	
+	// If using in-memory storage
+	if s.useInMemory {
+		for _, article := range s.inMemoryArticles {
+			for _, key := range []string{article.Snapshot, article.ListingSnapshot} {
+				if key != "" {
+					keys[key] = true
+				}
+			}
+		}
+		return keys, nil
+	}
+	
+	// Use MongoDB
+	ctx := context.Background()
+	
+	for _, field := range []string{"snapshot", "listing_snapshot"} {
+		values, err := s.articles.Distinct(ctx, field, bson.M{field: bson.M{"$nin": bson.A{nil, ""}}})
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range values {
+			if key, ok := value.(string); ok {
+				keys[key] = true
+			}
+		}
+	}
+	
+	return keys, nil
+}
+
+// reparsedArticle applies freshly extracted details to a stored article
+func reparsedArticle(stored ArticleWithContent, details scraper.ArticleDetails) ArticleWithContent {
+	article := scraper.Article{
+		ID:          stored.ID,
+		Title:       stored.Title,
+		URL:         stored.URL,
+		ImageURL:    stored.ImageURL,
+		Summary:     stored.Summary,
+		Source:      stored.Source,
+		Author:      stored.Author,
+		PublishedAt: stored.PublishedAt,
+	}.WithMetadata(details.Metadata)
+	
+	stored.CanonicalURL = article.CanonicalURL
+	stored.ImageURL = article.ImageURL
+	stored.Summary = article.Summary
+	stored.Author = article.Author
+	stored.ModifiedAt = article.ModifiedAt
+	if details.Content != "" {
+		stored.Content = details.Content
+	}
+	stored.Language = articleLanguage(stored)
+	
+	return stored
+}
+
+// articleLanguage detects the language of an article from all of its text
+func articleLanguage(article ArticleWithContent) string {
+	return textutil.DetectLanguage(article.Title + "\n" + article.Summary + "\n" + article.Content)
+}
+
+// DetectLanguages sets the language of stored articles that were ingested
+// before language detection existed. Articles that already went through
+// detection are skipped, so it is safe to run on every start. It returns the
+// number of articles updated.
+func (s *Storage) DetectLanguages() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	// If using in-memory storage
+	if s.useInMemory {
+		detected := 0
+		for id, article := range s.inMemoryArticles {
+			if article.Language != "" {
+				continue
+			}
+			if article.Language = articleLanguage(article); article.Language != "" {
+				s.inMemoryArticles[id] = article
+				detected++
+			}
+		}
+		return detected, nil
+	}
+	
+	// Use MongoDB
+	ctx := context.Background()
+	
+	projection := bson.M{"id": 1, "title": 1, "summary": 1, "content": 1}
+	cursor, err := s.articles.Find(ctx, bson.M{"language": bson.M{"$exists": false}}, options.Find().SetProjection(projection))
+	if err != nil {
+		return 0, err
+	}
+	
+	var articles []ArticleWithContent
+	err = cursor.All(ctx, &articles)
+	cursor.Close(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(articles) == 0 {
+		return 0, nil
+	}
+	
+	// Undetectable articles are stored with an empty language so they are
+	// not examined again
+	models := make([]mongo.WriteModel, 0, len(articles))
+	for _, article := range articles {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"id": article.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"language": articleLanguage(article)}}))
+	}
+	if _, err := s.articles.BulkWrite(ctx, models); err != nil {
+		return 0, err
+	}
+	
+	return len(articles), nil
+}
+
+// findArticlesByID returns the stored articles among the given IDs, keyed by ID
+func (s *Storage) findArticlesByID(ids []string) (map[string]ArticleWithContent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	found := make(map[string]ArticleWithContent)
+	
+	// If using in-memory storage
+	if s.useInMemory {
+		for _, id := range ids {
+			if article, exists := s.inMemoryArticleByID(id); exists {
+				found[id] = article
+			}
+		}
+		return found, nil
+	}
+	
+	// Use MongoDB
+	ctx := context.Background()
+	
+	// Content is not needed to decide whether to re-fetch
+	findOptions := options.Find().SetProjection(bson.M{"content": 0})
+	filter := bson.M{"$or": bson.A{
+		bson.M{"id": bson.M{"$in": ids}},
+		bson.M{"aliases": bson.M{"$in": ids}},
+	}}
+	cursor, err := s.articles.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	
+	var articles []ArticleWithContent
+	if err = cursor.All(ctx, &articles); err != nil {
+		return nil, err
+	}
+	
+	requested := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		requested[id] = true
+	}
+	
+	// Key each article by whichever requested ID matched it
+	for _, article := range articles {
+		for _, alias := range article.Aliases {
+			if requested[alias] {
+				found[alias] = article
+			}
+		}
+	}
+	for _, article := range articles {
+		found[article.ID] = article
+	}
+	
+	return found, nil
+}
+
+// inMemoryArticleByID finds an in-memory article by ID or alias. Callers must hold s.mu.
+func (s *Storage) inMemoryArticleByID(id string) (ArticleWithContent, bool) {
+	if article, exists := s.inMemoryArticles[id]; exists {
+		return article, true
+	}
+	
+	for _, article := range s.inMemoryArticles {
+		for _, alias := range article.Aliases {
+			if alias == id {
+				return article, true
+			}
+		}
+	}
+	return ArticleWithContent{}, false
+}
+
+// resolveArticleID maps an alias to the current article ID, returning the ID
+// unchanged when no article has it as an alias. Callers must hold s.mu.
+func (s *Storage) resolveArticleID(id string) (string, error) {
+	// If using in-memory storage
+	if s.useInMemory {
+		if article, exists := s.inMemoryArticleByID(id); exists {
+			return article.ID, nil
+		}
+		return id, nil
+	}
+	
+	// Use MongoDB
+	ctx := context.Background()
+	
+	var article ArticleWithContent
+	findOptions := options.FindOne().SetProjection(bson.M{"id": 1})
+	err := s.articles.FindOne(ctx, bson.M{"aliases": id}, findOptions).Decode(&article)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return id, nil
+		}
+		return id, err
+	}
+	
+	return article.ID, nil
+}
+
+// touchArticles refreshes last_seen_at for articles seen again in a listing
+func (s *Storage) touchArticles(ids []string, seenAt time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	// If using in-memory storage
+	if s.useInMemory {
+		for _, id := range ids {
+			if article, exists := s.inMemoryArticles[id]; exists {
+				article.LastSeenAt = seenAt
+				s.inMemoryArticles[id] = article
+			}
+		}
+		return nil
+	}
+	
+	// Use MongoDB
+	ctx := context.Background()
+	
+	_, err := s.articles.UpdateMany(
+		ctx,
+		bson.M{"id": bson.M{"$in": ids}},
+		bson.M{"$set": bson.M{"last_seen_at": seenAt}},
+	)
+	
+	return err
+}
+
+// saveArticles upserts already scraped articles, holding the lock only for the write.
+//
+// The articles were read and merged before their detail pages were fetched,
+// and another ingest (a backfill, or a run in another process) may have saved
+// them since. So the write merges with the document as it is stored now
+// instead of replacing it: aliases accumulate, the earliest first-seen time
+// wins, and content is never replaced by an empty one (a failed detail fetch).
+func (s *Storage) saveArticles(articles []ArticleWithContent) error {
+	if len(articles) == 0 {
+		return nil
+	}
+	
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	// If using in-memory storage
+	if s.useInMemory {
+		for _, article := range articles {
+			if stored, exists := s.inMemoryArticles[article.ID]; exists {
+				article.Aliases = mergeAliases(article.ID, stored.Aliases, article.Aliases)
+				if !stored.FirstSeenAt.IsZero() && stored.FirstSeenAt.Before(article.FirstSeenAt) {
+					article.FirstSeenAt = stored.FirstSeenAt
+				}
+				if article.Content == "" {
+					article.Content = stored.Content
+				}
+			}
+			s.inMemoryArticles[article.ID] = article
+		}
+		return nil
+	}
+	
+	// Use MongoDB
+	ctx := context.Background()
+	
+	models := make([]mongo.WriteModel, 0, len(articles))
+	for _, article := range articles {
+		aliases := article.Aliases
+		article.Aliases = nil
+		
+		fields, err := toDocument(article)
+		if err != nil {
+			return err
+		}
+		delete(fields, "first_seen_at")
+		if article.Content == "" {
+			delete(fields, "content")
+		}
+		
+		update := bson.M{"$set": fields}
+		if !article.FirstSeenAt.IsZero() {
+			update["$min"] = bson.M{"first_seen_at": article.FirstSeenAt}
+		}
+		if len(aliases) > 0 {
+			update["$addToSet"] = bson.M{"aliases": bson.M{"$each": aliases}}
+		}
+		
+		model := mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"id": article.ID}).
+			SetUpdate(update).
+			SetUpsert(true)
+		
+		models = append(models, model)
+	}
+	
+	_, err := s.articles.BulkWrite(ctx, models)
+	return err
+}
+
+// toDocument converts a value to the document MongoDB would store for it
+func toDocument(value interface{}) (bson.M, error) {
+	data, err := bson.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	
+	var doc bson.M
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
 }
 
-// AddArticle adds a new article to storage
-func (s *Storage) AddArticle(article scraper.Article, content string) error {
+// moveArticles removes the documents of articles that moved to a new ID,
+// given as old ID to new ID, and rewrites their bookmarks to the new ID
+func (s *Storage) moveArticles(moved map[string]string) error {
+	if len(moved) == 0 {
+		return nil
+	}
+	
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	
 	// If using in-memory storage
 	if s.useInMemory {
-		articleWithContent := ArticleWithContent{
-			ID:          article.ID,
-			Title:       article.Title,
-			URL:         article.URL,
-			ImageURL:    article.ImageURL,
-			Summary:     article.Summary,
-			Source:      article.Source,
-			PublishedAt: article.PublishedAt,
-			Content:     content,
+		for oldID := range moved {
+			delete(s.inMemoryArticles, oldID)
+		}
+		for userID, articleIDs := range s.inMemoryBookmarks {
+			rewritten := make([]string, 0, len(articleIDs))
+			seen := make(map[string]bool)
+			for _, articleID := range articleIDs {
+				if newID, ok := moved[articleID]; ok {
+					articleID = newID
+				}
+				if !seen[articleID] {
+					seen[articleID] = true
+					rewritten = append(rewritten, articleID)
+				}
+			}
+			s.inMemoryBookmarks[userID] = rewritten
 		}
-		s.inMemoryArticles[article.ID] = articleWithContent
 		return nil
 	}
 	
 	// Use MongoDB
 	ctx := context.Background()
-	articleWithContent := ArticleWithContent{
-		ID:          article.ID,
-		Title:       article.Title,
-		URL:         article.URL,
-		ImageURL:    article.ImageURL,
-		Summary:     article.Summary,
-		Source:      article.Source,
-		PublishedAt: article.PublishedAt,
-		Content:     content,
-	}
 	
-	_, err := s.articles.UpdateOne(
-		ctx,
-		bson.M{"id": article.ID},
-		bson.M{"$set": articleWithContent},
-		options.Update().SetUpsert(true),
-	)
+	oldIDs := make([]string, 0, len(moved))
+	for oldID, newID := range moved {
+		oldIDs = append(oldIDs, oldID)
+		_, err := s.bookmarks.UpdateMany(
+			ctx,
+			bson.M{"article_id": oldID},
+			bson.M{"$set": bson.M{"article_id": newID}},
+		)
+		if err != nil {
+			return err
+		}
+	}
 	
+	_, err := s.articles.DeleteMany(ctx, bson.M{"id": bson.M{"$in": oldIDs}})
 	return err
 }
 
-// AddArticles adds multiple articles to storage
-func (s *Storage) AddArticles(articles []scraper.Article, scraperInstance *scraper.Scraper) error {
+// MigrateArticleIDs moves articles stored under legacy 8-character IDs to the
+// current collision-safe IDs derived from their canonical URL. The old ID is
+// kept as an alias so existing links keep working, and bookmarks are rewritten
+// to the new ID. Articles whose URLs canonicalize to the same ID are merged.
+// Already migrated articles are skipped, so it is safe to run on every start.
+func (s *Storage) MigrateArticleIDs() (int, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	
+	migrated := 0
+	
 	// If using in-memory storage
 	if s.useInMemory {
-		for _, article := range articles {
-			content, _ := scraperInstance.ScrapeGameDetails(article.URL)
-			articleWithContent := ArticleWithContent{
-				ID:          article.ID,
-				Title:       article.Title,
-				URL:         article.URL,
-				ImageURL:    article.ImageURL,
-				Summary:     article.Summary,
-				Source:      article.Source,
-				PublishedAt: article.PublishedAt,
-				Content:     content,
-			}
-			s.inMemoryArticles[article.ID] = articleWithContent
+		for id, article := range s.inMemoryArticles {
+			if len(id) != legacyIDLength {
+				continue
+			}
+			
+			newID, aliases := migratedArticleID(article)
+			if target, exists := s.inMemoryArticles[newID]; exists {
+				target.Aliases = mergeAliases(newID, target.Aliases, aliases)
+				s.inMemoryArticles[newID] = target
+			} else {
+				article.ID = newID
+				article.Aliases = aliases
+				s.inMemoryArticles[newID] = article
+			}
+			delete(s.inMemoryArticles, id)
+			
+			for _, articleIDs := range s.inMemoryBookmarks {
+				for i := range articleIDs {
+					if articleIDs[i] == id {
+						articleIDs[i] = newID
+					}
+				}
+			}
+			migrated++
 		}
-		return nil
+		return migrated, nil
 	}
 	
 	// Use MongoDB
 	ctx := context.Background()
 	
-	var models []mongo.WriteModel
-	for _, article := range articles {
-		content, _ := scraperInstance.ScrapeGameDetails(article.URL)
-		articleWithContent := ArticleWithContent{
-			ID:          article.ID,
-			Title:       article.Title,
-			URL:         article.URL,
-			ImageURL:    article.ImageURL,
-			Summary:     article.Summary,
-			Source:      article.Source,
-			PublishedAt: article.PublishedAt,
-			Content:     content,
+	filter := bson.M{"id": bson.M{"$regex": fmt.Sprintf("^[0-9a-f]{%d}$", legacyIDLength)}}
+	cursor, err := s.articles.Find(ctx, filter, options.Find().SetProjection(bson.M{"content": 0}))
+	if err != nil {
+		return 0, err
+	}
+	
+	var legacy []ArticleWithContent
+	err = cursor.All(ctx, &legacy)
+	cursor.Close(ctx)
+	if err != nil {
+		return 0, err
+	}
+	
+	for _, article := range legacy {
+		newID, aliases := migratedArticleID(article)
+		addAliases := bson.M{"aliases": bson.M{"$each": aliases}}
+		
+		count, err := s.articles.CountDocuments(ctx, bson.M{"id": newID})
+		if err != nil {
+			return migrated, err
 		}
 		
-		model := mongo.NewUpdateOneModel().
-			SetFilter(bson.M{"id": article.ID}).
-			SetUpdate(bson.M{"$set": articleWithContent}).
-			SetUpsert(true)
+		if count > 0 {
+			// Another legacy article already canonicalized to the same ID
+			if _, err := s.articles.UpdateOne(ctx, bson.M{"id": newID}, bson.M{"$addToSet": addAliases}); err != nil {
+				return migrated, err
+			}
+			if _, err := s.articles.DeleteOne(ctx, bson.M{"id": article.ID}); err != nil {
+				return migrated, err
+			}
+		} else {
+			update := bson.M{"$set": bson.M{"id": newID}, "$addToSet": addAliases}
+			if _, err := s.articles.UpdateOne(ctx, bson.M{"id": article.ID}, update); err != nil {
+				return migrated, err
+			}
+		}
 		
-		models = append(models, model)
+		_, err = s.bookmarks.UpdateMany(
+			ctx,
+			bson.M{"article_id": article.ID},
+			bson.M{"$set": bson.M{"article_id": newID}},
+		)
+		if err != nil {
+			return migrated, err
+		}
+		
+		migrated++
 	}
 	
-	_, err := s.articles.BulkWrite(ctx, models)
-	return err
+	return migrated, nil
+}
+
+// migratedArticleID returns the current ID of a legacy article and the
+// aliases it should keep: the legacy ID and its listing link ID
+func migratedArticleID(article ArticleWithContent) (string, []string) {
+	listingID := scraper.ArticleID(article.URL)
+	newID := scraper.CanonicalArticleID(scraper.Article{
+		ID:           listingID,
+		URL:          article.URL,
+		CanonicalURL: article.CanonicalURL,
+	})
+	
+	return newID, mergeAliases(newID, article.Aliases, []string{article.ID, listingID})
 }
 
 // GetArticles returns all articles
@@ -299,7 +1408,7 @@ func (s *Storage) GetArticleByID(id string) (ArticleWithContent, bool, error) {
 	
 	// If using in-memory storage
 	if s.useInMemory {
-		article, exists := s.inMemoryArticles[id]
+		article, exists := s.inMemoryArticleByID(id)
 		return article, exists, nil
 	}
 	
@@ -308,6 +1417,10 @@ func (s *Storage) GetArticleByID(id string) (ArticleWithContent, bool, error) {
 	
 	var article ArticleWithContent
 	err := s.articles.FindOne(ctx, bson.M{"id": id}).Decode(&article)
+	if err == mongo.ErrNoDocuments {
+		// Fall back to legacy and listing IDs
+		err = s.articles.FindOne(ctx, bson.M{"aliases": id}).Decode(&article)
+	}
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return article, false, nil
@@ -318,20 +1431,32 @@ func (s *Storage) GetArticleByID(id string) (ArticleWithContent, bool, error) {
 	return article, true, nil
 }
 
-// SearchArticles searches articles by query
-func (s *Storage) SearchArticles(query string) ([]ArticleWithContent, error) {
+// SearchArticles searches articles by query, limited to one language unless
+// language is empty
+//
+// The query is split into terms with textutil.SearchTerms: words for
+// space-separated languages and overlapping character pairs for Chinese and
+// Japanese, so Chinese queries match without word boundaries. An article
+// matches when its title, summary or content contains every term; words
+// match the start of a word, so "retr" finds "Retro" (textutil.MatchesTerms).
+func (s *Storage) SearchArticles(query, language string) ([]ArticleWithContent, error) {
+	terms := textutil.SearchTerms(query)
+	if len(terms) == 0 {
+		return make([]ArticleWithContent, 0), nil
+	}
+	
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	
 	// If using in-memory storage
 	if s.useInMemory {
 		articles := make([]ArticleWithContent, 0)
-		searchTerm := strings.ToLower(query)
 		
 		for _, article := range s.inMemoryArticles {
-			if strings.Contains(strings.ToLower(article.Title), searchTerm) ||
-				strings.Contains(strings.ToLower(article.Summary), searchTerm) ||
-				strings.Contains(strings.ToLower(article.Content), searchTerm) {
+			if language != "" && article.Language != language {
+				continue
+			}
+			if textutil.MatchesTerms(article.Title+"\n"+article.Summary+"\n"+article.Content, terms) {
 				articles = append(articles, article)
 			}
 		}
@@ -351,14 +1476,22 @@ func (s *Storage) SearchArticles(query string) ([]ArticleWithContent, error) {
 	// Use MongoDB
 	ctx := context.Background()
 	
-	searchRegex := bson.M{"$regex": query, "$options": "i"}
-	filter := bson.M{
-		"$or": []bson.M{
-			{"title": searchRegex},
-			{"summary": searchRegex},
-			{"content": searchRegex},
-		},
+	// Every term has to appear in one of the text fields
+	conditions := make([]bson.M, 0, len(terms)+1)
+	for _, term := range terms {
+		searchRegex := bson.M{"$regex": textutil.TermPattern(term), "$options": "i"}
+		conditions = append(conditions, bson.M{
+			"$or": []bson.M{
+				{"title": searchRegex},
+				{"summary": searchRegex},
+				{"content": searchRegex},
+			},
+		})
+	}
+	if language != "" {
+		conditions = append(conditions, bson.M{"language": language})
 	}
+	filter := bson.M{"$and": conditions}
 	
 	cursor, err := s.articles.Find(ctx, filter, options.Find().SetSort(bson.D{{"published_at", -1}}))
 	if err != nil {
@@ -374,8 +1507,9 @@ func (s *Storage) SearchArticles(query string) ([]ArticleWithContent, error) {
 	return articles, nil
 }
 
-// FilterArticlesBySource filters articles by source
-func (s *Storage) FilterArticlesBySource(source string) ([]ArticleWithContent, error) {
+// FilterArticlesBySource filters articles by source, limited to one language
+// unless language is empty
+func (s *Storage) FilterArticlesBySource(source, language string) ([]ArticleWithContent, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	
@@ -384,7 +1518,7 @@ func (s *Storage) FilterArticlesBySource(source string) ([]ArticleWithContent, e
 		articles := make([]ArticleWithContent, 0)
 		
 		for _, article := range s.inMemoryArticles {
-			if article.Source == source {
+			if article.Source == source && (language == "" || article.Language == language) {
 				articles = append(articles, article)
 			}
 		}
@@ -404,7 +1538,12 @@ func (s *Storage) FilterArticlesBySource(source string) ([]ArticleWithContent, e
 	// Use MongoDB
 	ctx := context.Background()
 	
-	cursor, err := s.articles.Find(ctx, bson.M{"source": source}, options.Find().SetSort(bson.D{{"published_at", -1}}))
+	filter := bson.M{"source": source}
+	if language != "" {
+		filter["language"] = language
+	}
+	
+	cursor, err := s.articles.Find(ctx, filter, options.Find().SetSort(bson.D{{"published_at", -1}}))
 	if err != nil {
 		return nil, err
 	}
@@ -418,8 +1557,9 @@ func (s *Storage) FilterArticlesBySource(source string) ([]ArticleWithContent, e
 	return articles, nil
 }
 
-// GetRecentArticles returns the most recent articles
-func (s *Storage) GetRecentArticles(limit int) ([]ArticleWithContent, error) {
+// GetRecentArticles returns the most recent articles, limited to one language
+// unless language is empty
+func (s *Storage) GetRecentArticles(limit int, language string) ([]ArticleWithContent, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	
@@ -427,7 +1567,9 @@ func (s *Storage) GetRecentArticles(limit int) ([]ArticleWithContent, error) {
 	if s.useInMemory {
 		articles := make([]ArticleWithContent, 0, len(s.inMemoryArticles))
 		for _, article := range s.inMemoryArticles {
-			articles = append(articles, article)
+			if language == "" || article.Language == language {
+				articles = append(articles, article)
+			}
 		}
 		
 		// Sort by published date (newest first)
@@ -455,7 +1597,12 @@ func (s *Storage) GetRecentArticles(limit int) ([]ArticleWithContent, error) {
 		findOptions.SetLimit(int64(limit))
 	}
 	
-	cursor, err := s.articles.Find(ctx, bson.M{}, findOptions)
+	filter := bson.M{}
+	if language != "" {
+		filter["language"] = language
+	}
+	
+	cursor, err := s.articles.Find(ctx, filter, findOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -485,6 +1632,11 @@ func (s *Storage) Cleanup(olderThan time.Duration) (int64, error) {
 				count++
 			}
 		}
+		for id, story := range s.inMemoryStories {
+			if story.LastPublishedAt.Before(cutoff) {
+				delete(s.inMemoryStories, id)
+			}
+		}
 		return count, nil
 	}
 	
@@ -496,6 +1648,11 @@ func (s *Storage) Cleanup(olderThan time.Duration) (int64, error) {
 		return 0, err
 	}
 	
+	// Stories whose newest article is gone have no members left
+	if _, err := s.stories.DeleteMany(ctx, bson.M{"last_published_at": bson.M{"$lt": cutoff}}); err != nil {
+		return result.DeletedCount, err
+	}
+	
 	return result.DeletedCount, nil
 }
 
@@ -575,6 +1732,12 @@ func (s *Storage) AddBookmark(userID int64, articleID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	
+	// Bookmarks always point at the current article ID
+	articleID, err := s.resolveArticleID(articleID)
+	if err != nil {
+		return err
+	}
+	
 	// If using in-memory storage
 	if s.useInMemory {
 		// Check if bookmark already exists
@@ -602,7 +1765,7 @@ func (s *Storage) AddBookmark(userID int64, articleID string) error {
 	}
 	
 	// Use upsert to avoid duplicates
-	_, err := s.bookmarks.UpdateOne(
+	_, err = s.bookmarks.UpdateOne(
 		ctx,
 		bson.M{"user_id": userID, "article_id": articleID},
 		bson.M{"$set": bookmark},
@@ -613,30 +1776,70 @@ func (s *Storage) AddBookmark(userID int64, articleID string) error {
 }
 
 // RemoveBookmark removes a bookmark
+//
+// Bookmarks made under any of the article's IDs are removed, including ones
+// that still point at an ID the article has since moved away from.
 func (s *Storage) RemoveBookmark(userID int64, articleID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	
+	articleIDs, err := s.articleIDs(articleID)
+	if err != nil {
+		return err
+	}
+	
 	// If using in-memory storage
 	if s.useInMemory {
-		bookmarks := s.inMemoryBookmarks[userID]
-		for i, bookmarkedArticleID := range bookmarks {
-			if bookmarkedArticleID == articleID {
-				// Remove the bookmark
-				s.inMemoryBookmarks[userID] = append(bookmarks[:i], bookmarks[i+1:]...)
-				break
+		remove := make(map[string]bool, len(articleIDs))
+		for _, id := range articleIDs {
+			remove[id] = true
+		}
+		
+		bookmarks := make([]string, 0, len(s.inMemoryBookmarks[userID]))
+		for _, bookmarkedArticleID := range s.inMemoryBookmarks[userID] {
+			if !remove[bookmarkedArticleID] {
+				bookmarks = append(bookmarks, bookmarkedArticleID)
 			}
 		}
+		s.inMemoryBookmarks[userID] = bookmarks
 		return nil
 	}
 	
 	// Use MongoDB
 	ctx := context.Background()
 	
-	_, err := s.bookmarks.DeleteOne(ctx, bson.M{"user_id": userID, "article_id": articleID})
+	_, err = s.bookmarks.DeleteMany(ctx, bson.M{"user_id": userID, "article_id": bson.M{"$in": articleIDs}})
 	return err
 }
 
+// articleIDs returns the current ID and the aliases of the article with the
+// given ID or alias, or just the given ID when no such article is stored
+func (s *Storage) articleIDs(id string) ([]string, error) {
+	// If using in-memory storage
+	if s.useInMemory {
+		if article, exists := s.inMemoryArticleByID(id); exists {
+			return append([]string{article.ID}, article.Aliases...), nil
+		}
+		return []string{id}, nil
+	}
+	
+	// Use MongoDB
+	ctx := context.Background()
+	
+	var article ArticleWithContent
+	filter := bson.M{"$or": bson.A{bson.M{"id": id}, bson.M{"aliases": id}}}
+	findOptions := options.FindOne().SetProjection(bson.M{"id": 1, "aliases": 1})
+	err := s.articles.FindOne(ctx, filter, findOptions).Decode(&article)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return []string{id}, nil
+		}
+		return nil, err
+	}
+	
+	return append([]string{article.ID}, article.Aliases...), nil
+}
+
 // GetBookmarks gets user bookmarks
 func (s *Storage) GetBookmarks(userID int64) ([]ArticleWithContent, error) {
 	s.mu.RLock()
@@ -648,7 +1851,7 @@ func (s *Storage) GetBookmarks(userID int64) ([]ArticleWithContent, error) {
 		articles := make([]ArticleWithContent, 0, len(articleIDs))
 		
 		for _, articleID := range articleIDs {
-			if article, exists := s.inMemoryArticles[articleID]; exists {
+			if article, exists := s.inMemoryArticleByID(articleID); exists {
 				articles = append(articles, article)
 			}
 		}
@@ -680,8 +1883,11 @@ func (s *Storage) GetBookmarks(userID int64) ([]ArticleWithContent, error) {
 		articleIDs[i] = bookmark.ArticleID
 	}
 	
-	// Then get the articles
-	filter := bson.M{"id": bson.M{"$in": articleIDs}}
+	// Then get the articles, also matching bookmarks made with legacy IDs
+	filter := bson.M{"$or": bson.A{
+		bson.M{"id": bson.M{"$in": articleIDs}},
+		bson.M{"aliases": bson.M{"$in": articleIDs}},
+	}}
 	cursor, err = s.articles.Find(ctx, filter, options.Find().SetSort(bson.D{{"published_at", -1}}))
 	if err != nil {
 		return nil, err
@@ -694,4 +1900,323 @@ func (s *Storage) GetBookmarks(userID int64) ([]ArticleWithContent, error) {
 	}
 	
 	return articles, nil
-}
\ No newline at end of file
+}
+
+// newScrapeRun converts a scraper report into the stored form
+func newScrapeRun(report scraper.SourceReport) ScrapeRun {
+	statuses := make(map[string]int, len(report.Statuses))
+	for code, count := range report.Statuses {
+		statuses[strconv.Itoa(code)] = count
+	}
+	
+	return ScrapeRun{
+		RunID:       report.RunID,
+		Source:      report.Source,
+		StartedAt:   report.StartedAt,
+		DurationMs:  report.Duration.Milliseconds(),
+		URLsVisited: report.URLsVisited,
+		Statuses:    statuses,
+		ItemsFound:  report.ItemsFound,
+		ItemsParsed: report.ItemsParsed,
+		FieldCounts: report.FieldCounts,
+		Incremental: report.Incremental,
+		Unchanged:   report.Unchanged,
+		Errors:      report.Errors,
+		Snapshots:   report.Snapshots,
+		Stored:      report.Stored,
+	}
+}
+
+// Report converts a stored run back into a scraper report
+func (r ScrapeRun) Report() scraper.SourceReport {
+	statuses := make(map[int]int, len(r.Statuses))
+	for code, count := range r.Statuses {
+		if status, err := strconv.Atoi(code); err == nil {
+			statuses[status] = count
+		}
+	}
+	
+	return scraper.SourceReport{
+		RunID:       r.RunID,
+		Source:      r.Source,
+		StartedAt:   r.StartedAt,
+		Duration:    time.Duration(r.DurationMs) * time.Millisecond,
+		URLsVisited: r.URLsVisited,
+		Statuses:    statuses,
+		ItemsFound:  r.ItemsFound,
+		ItemsParsed: r.ItemsParsed,
+		FieldCounts: r.FieldCounts,
+		Incremental: r.Incremental,
+		Unchanged:   r.Unchanged,
+		Errors:      r.Errors,
+		Snapshots:   r.Snapshots,
+		Stored:      r.Stored,
+	}
+}
+
+// SaveScrapeRuns stores the per-source reports of a scrape run
+func (s *Storage) SaveScrapeRuns(reports []scraper.SourceReport) error {
+	if len(reports) == 0 {
+		return nil
+	}
+	
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	// If using in-memory storage
+	if s.useInMemory {
+		for _, report := range reports {
+			// Newest first, capped per source
+			runs := append([]ScrapeRun{newScrapeRun(report)}, s.inMemoryScrapeRuns[report.Source]...)
+			if len(runs) > maxScrapeRunsPerSource {
+				runs = runs[:maxScrapeRunsPerSource]
+			}
+			s.inMemoryScrapeRuns[report.Source] = runs
+		}
+		return nil
+	}
+	
+	// Use MongoDB
+	ctx := context.Background()
+	
+	documents := make([]interface{}, len(reports))
+	for i, report := range reports {
+		documents[i] = newScrapeRun(report)
+	}
+	
+	if _, err := s.scrapeRuns.InsertMany(ctx, documents); err != nil {
+		return err
+	}
+	
+	// Apply the same per-source cap as the in-memory history
+	for _, report := range reports {
+		if err := s.pruneScrapeRuns(ctx, report.Source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneScrapeRuns deletes the runs of a source beyond the newest
+// maxScrapeRunsPerSource
+func (s *Storage) pruneScrapeRuns(ctx context.Context, source string) error {
+	findOptions := options.FindOne().
+		SetSort(bson.D{{"started_at", -1}}).
+		SetSkip(maxScrapeRunsPerSource).
+		SetProjection(bson.M{"started_at": 1})
+	
+	var oldest ScrapeRun
+	err := s.scrapeRuns.FindOne(ctx, bson.M{"source": source}, findOptions).Decode(&oldest)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	
+	_, err = s.scrapeRuns.DeleteMany(ctx, bson.M{
+		"source":     source,
+		"started_at": bson.M{"$lte": oldest.StartedAt},
+	})
+	return err
+}
+
+// GetScrapeRuns returns the last limit runs of every source, newest first
+func (s *Storage) GetScrapeRuns(limit int) (map[string][]ScrapeRun, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	runsBySource := make(map[string][]ScrapeRun)
+	
+	// If using in-memory storage
+	if s.useInMemory {
+		for source, runs := range s.inMemoryScrapeRuns {
+			if limit > 0 && limit < len(runs) {
+				runs = runs[:limit]
+			}
+			runsBySource[source] = append([]ScrapeRun(nil), runs...)
+		}
+		return runsBySource, nil
+	}
+	
+	// Use MongoDB
+	ctx := context.Background()
+	
+	sources, err := s.scrapeRuns.Distinct(ctx, "source", bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	
+	for _, value := range sources {
+		source, ok := value.(string)
+		if !ok {
+			continue
+		}
+		
+		findOptions := options.Find().SetSort(bson.D{{"started_at", -1}})
+		if limit > 0 {
+			findOptions.SetLimit(int64(limit))
+		}
+		
+		cursor, err := s.scrapeRuns.Find(ctx, bson.M{"source": source}, findOptions)
+		if err != nil {
+			return nil, err
+		}
+		
+		var runs []ScrapeRun
+		err = cursor.All(ctx, &runs)
+		cursor.Close(ctx)
+		if err != nil {
+			return nil, err
+		}
+		
+		runsBySource[source] = runs
+	}
+	
+	return runsBySource, nil
+}
+
+// GetSourceHistory returns the last limit reports of a single source, newest first
+func (s *Storage) GetSourceHistory(source string, limit int) ([]scraper.SourceReport, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	var runs []ScrapeRun
+	
+	// If using in-memory storage
+	if s.useInMemory {
+		runs = s.inMemoryScrapeRuns[source]
+		if limit > 0 && limit < len(runs) {
+			runs = runs[:limit]
+		}
+	} else {
+		// Use MongoDB
+		ctx := context.Background()
+		
+		findOptions := options.Find().SetSort(bson.D{{"started_at", -1}})
+		if limit > 0 {
+			findOptions.SetLimit(int64(limit))
+		}
+		
+		cursor, err := s.scrapeRuns.Find(ctx, bson.M{"source": source}, findOptions)
+		if err != nil {
+			return nil, err
+		}
+		defer cursor.Close(ctx)
+		
+		if err = cursor.All(ctx, &runs); err != nil {
+			return nil, err
+		}
+	}
+	
+	reports := make([]scraper.SourceReport, len(runs))
+	for i, run := range runs {
+		reports[i] = run.Report()
+	}
+	
+	return reports, nil
+}
+
+// SaveSourceEvent stores a "source degraded" event
+func (s *Storage) SaveSourceEvent(event scraper.DegradedEvent) error {
+	stored := SourceEvent{
+		Type:       SourceEventDegraded,
+		Source:     event.Source,
+		RunID:      event.RunID,
+		DetectedAt: event.DetectedAt,
+		Reasons:    event.Reasons,
+	}
+	
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	// If using in-memory storage
+	if s.useInMemory {
+		// Newest first, capped
+		events := append([]SourceEvent{stored}, s.inMemorySourceEvents...)
+		if len(events) > maxSourceEvents {
+			events = events[:maxSourceEvents]
+		}
+		s.inMemorySourceEvents = events
+		return nil
+	}
+	
+	// Use MongoDB
+	ctx := context.Background()
+	
+	if _, err := s.sourceEvents.InsertOne(ctx, stored); err != nil {
+		return err
+	}
+	
+	// Apply the same cap as the in-memory events
+	return s.pruneSourceEvents(ctx)
+}
+
+// pruneSourceEvents deletes the events beyond the newest maxSourceEvents
+func (s *Storage) pruneSourceEvents(ctx context.Context) error {
+	findOptions := options.FindOne().
+		SetSort(bson.D{{"detected_at", -1}}).
+		SetSkip(maxSourceEvents).
+		SetProjection(bson.M{"detected_at": 1})
+	
+	var oldest SourceEvent
+	err := s.sourceEvents.FindOne(ctx, bson.M{}, findOptions).Decode(&oldest)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	
+	_, err = s.sourceEvents.DeleteMany(ctx, bson.M{
+		"detected_at": bson.M{"$lte": oldest.DetectedAt},
+	})
+	return err
+}
+
+// GetSourceEvents returns the latest source events, optionally for a single source
+func (s *Storage) GetSourceEvents(source string, limit int) ([]SourceEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	// If using in-memory storage
+	if s.useInMemory {
+		events := make([]SourceEvent, 0)
+		for _, event := range s.inMemorySourceEvents {
+			if source != "" && event.Source != source {
+				continue
+			}
+			events = append(events, event)
+			if limit > 0 && len(events) == limit {
+				break
+			}
+		}
+		return events, nil
+	}
+	
+	// Use MongoDB
+	ctx := context.Background()
+	
+	filter := bson.M{}
+	if source != "" {
+		filter["source"] = source
+	}
+	
+	findOptions := options.Find().SetSort(bson.D{{"detected_at", -1}})
+	if limit > 0 {
+		findOptions.SetLimit(int64(limit))
+	}
+	
+	cursor, err := s.sourceEvents.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	
+	events := make([]SourceEvent, 0)
+	if err = cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	
+	return events, nil
+}
@@ -0,0 +1,308 @@
+package storage
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"game-news/scraper"
+	"game-news/textutil"
+)
+
+const (
+	zeldaStory = "Nintendo has announced that the next Legend of Zelda game will launch on Switch 2 in March next year. " +
+		"The publisher showed a first trailer during its Direct presentation on Tuesday, revealing a new open world, " +
+		"a playable Zelda and a co-op mode for two players. Pre-orders open today in North America and Europe, " +
+		"with a collector's edition that includes an art book and a steelbook case."
+	cs2Story = "Valve has released a major update for Counter-Strike 2 that reworks the economy of competitive matches. " +
+		"Players now earn more money after losing rounds, and several rifles received price changes. The patch also " +
+		"fixes a number of crashes on Linux, improves the performance of smoke grenades and adds two community maps " +
+		"to the active duty pool for the next season."
+)
+
+// storedArticle returns an article as it was stored, with its fingerprint
+func storedArticle(id, source, content string, seen time.Time, duplicateOf string) ArticleWithContent {
+	article := ArticleWithContent{
+		ID:          id,
+		Source:      source,
+		Title:       "Zelda",
+		Content:     content,
+		PublishedAt: seen,
+		FirstSeenAt: seen,
+		DuplicateOf: duplicateOf,
+	}
+	article.Fingerprint = int64(textutil.SimHash(article.Title + "\n" + content))
+	return article
+}
+
+func TestMarkDuplicates(t *testing.T) {
+	base := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		recent   []ArticleWithContent
+		articles []ArticleWithContent
+		want     map[string]string
+	}{
+		{
+			name:     "later article from another source",
+			recent:   []ArticleWithContent{storedArticle("ign", "IGN", zeldaStory, base, "")},
+			articles: []ArticleWithContent{storedArticle("gamespot", "GameSpot", zeldaStory+" Source: Nintendo.", base.Add(time.Hour), "")},
+			want:     map[string]string{"gamespot": "ign"},
+		},
+		{
+			name: "duplicate of a duplicate points at the primary",
+			recent: []ArticleWithContent{
+				storedArticle("ign", "IGN", zeldaStory, base, ""),
+				storedArticle("gamespot", "GameSpot", zeldaStory, base.Add(time.Hour), "ign"),
+			},
+			articles: []ArticleWithContent{storedArticle("kotaku", "Kotaku", zeldaStory, base.Add(2*time.Hour), "")},
+			want:     map[string]string{"kotaku": "ign"},
+		},
+		{
+			name:     "same source",
+			recent:   []ArticleWithContent{storedArticle("ign", "IGN", zeldaStory, base, "")},
+			articles: []ArticleWithContent{storedArticle("ign-2", "IGN", zeldaStory, base.Add(time.Hour), "")},
+			want:     map[string]string{"ign-2": ""},
+		},
+		{
+			name:     "different story",
+			recent:   []ArticleWithContent{storedArticle("ign", "IGN", zeldaStory, base, "")},
+			articles: []ArticleWithContent{storedArticle("pcgamer", "PC Gamer", cs2Story, base.Add(time.Hour), "")},
+			want:     map[string]string{"pcgamer": ""},
+		},
+		{
+			name:     "outside the duplicate window",
+			recent:   []ArticleWithContent{storedArticle("ign", "IGN", zeldaStory, base, "")},
+			articles: []ArticleWithContent{storedArticle("gamespot", "GameSpot", zeldaStory, base.Add(duplicateWindow+time.Hour), "")},
+			want:     map[string]string{"gamespot": ""},
+		},
+		{
+			name:     "never a duplicate of an article seen later",
+			recent:   []ArticleWithContent{storedArticle("gamespot", "GameSpot", zeldaStory, base.Add(time.Hour), "")},
+			articles: []ArticleWithContent{storedArticle("ign", "IGN", zeldaStory, base, "")},
+			want:     map[string]string{"ign": ""},
+		},
+		{
+			name: "primary stays primary when scraped again",
+			recent: []ArticleWithContent{
+				storedArticle("polygon", "Polygon", zeldaStory, base.Add(-time.Hour), ""),
+				storedArticle("ign", "IGN", zeldaStory, base, ""),
+				storedArticle("gamespot", "GameSpot", zeldaStory, base.Add(time.Hour), "ign"),
+			},
+			articles: []ArticleWithContent{storedArticle("ign", "IGN", zeldaStory, base, "")},
+			want:     map[string]string{"ign": ""},
+		},
+		{
+			name:   "earlier article in the same batch is the primary",
+			recent: []ArticleWithContent{},
+			articles: []ArticleWithContent{
+				storedArticle("ign", "IGN", zeldaStory, base, ""),
+				storedArticle("gamespot", "GameSpot", zeldaStory, base.Add(time.Hour), ""),
+			},
+			want: map[string]string{"ign": "", "gamespot": "ign"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			markDuplicates(tt.articles, tt.recent)
+
+			for _, article := range tt.articles {
+				want, ok := tt.want[article.ID]
+				if !ok {
+					t.Fatalf("unexpected article %s", article.ID)
+				}
+				if article.DuplicateOf != want {
+					t.Errorf("%s.DuplicateOf = %q, want %q", article.ID, article.DuplicateOf, want)
+				}
+				if article.Fingerprint == 0 {
+					t.Errorf("%s has no fingerprint", article.ID)
+				}
+			}
+		})
+	}
+}
+
+// articleSite serves article pages and counts the requests for each path
+type articleSite struct {
+	mu       sync.Mutex
+	pages    map[string]string
+	requests map[string]int
+}
+
+func newArticleSite(t *testing.T, pages map[string]string) (*articleSite, *httptest.Server) {
+	site := &articleSite{pages: pages, requests: make(map[string]int)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		site.mu.Lock()
+		defer site.mu.Unlock()
+		site.requests[r.URL.Path]++
+		page, ok := site.pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	}))
+	t.Cleanup(server.Close)
+	return site, server
+}
+
+func (s *articleSite) setPage(path, page string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if page == "" {
+		delete(s.pages, path)
+	} else {
+		s.pages[path] = page
+	}
+}
+
+func (s *articleSite) requestCount(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+// newTestScraper returns a scraper that fetches without politeness delays
+func newTestScraper() *scraper.Scraper {
+	return scraper.NewScraperWithConfig(&scraper.Config{
+		Politeness: scraper.PolitenessConfig{Default: scraper.DomainPolicy{Parallelism: 4}},
+	})
+}
+
+const zeldaPage = `<html><head><title>Zelda sequel announced</title>
+<meta property="article:modified_time" content="2024-03-06T08:00:00Z"></head>
+<body><article><p>` + zeldaStory + `</p></article></body></html>`
+
+func TestAddArticlesKnownArticles(t *testing.T) {
+	t.Setenv("MONGO_URI", "")
+	s := NewStorage()
+	site, server := newArticleSite(t, map[string]string{"/news/zelda": zeldaPage})
+	scr := newTestScraper()
+
+	link := server.URL + "/news/zelda"
+	listing := scraper.Article{ID: scraper.ArticleID(link), Title: "Zelda sequel announced", URL: link, Source: "Example"}
+	modified := time.Date(2024, time.March, 6, 8, 0, 0, 0, time.UTC)
+
+	stored := func() ArticleWithContent {
+		t.Helper()
+		article, ok := s.inMemoryArticles[listing.ID]
+		if !ok {
+			t.Fatal("article is not stored")
+		}
+		return article
+	}
+
+	if err := s.AddArticles([]scraper.Article{listing}, scr); err != nil {
+		t.Fatalf("AddArticles() error = %v", err)
+	}
+	first := stored()
+	if first.Content != zeldaStory || !first.ModifiedAt.Equal(modified) || first.ListingHash == "" ||
+		first.FirstSeenAt.IsZero() || !first.LastSeenAt.Equal(first.FirstSeenAt) {
+		t.Fatalf("new article = %+v", first)
+	}
+
+	// An unchanged listing only refreshes last_seen_at
+	if err := s.AddArticles([]scraper.Article{listing}, scr); err != nil {
+		t.Fatalf("AddArticles() error = %v", err)
+	}
+	if n := site.requestCount("/news/zelda"); n != 1 {
+		t.Errorf("unchanged article fetched %d times, want 1", n)
+	}
+	seen := stored()
+	if !seen.FirstSeenAt.Equal(first.FirstSeenAt) || !seen.LastSeenAt.After(first.LastSeenAt) || seen.Content != zeldaStory {
+		t.Errorf("unchanged article = %+v", seen)
+	}
+
+	// A changed listing whose detail fetch fails keeps the stored details
+	// and is retried on the next run
+	listing.Summary = "Nintendo confirmed it."
+	site.setPage("/news/zelda", "")
+	if err := s.AddArticles([]scraper.Article{listing}, scr); err != nil {
+		t.Fatalf("AddArticles() error = %v", err)
+	}
+	if n := site.requestCount("/news/zelda"); n != 2 {
+		t.Errorf("changed article fetched %d times in total, want 2", n)
+	}
+	failed := stored()
+	if failed.Content != zeldaStory || !failed.ModifiedAt.Equal(modified) || failed.ListingHash != "" ||
+		!failed.FirstSeenAt.Equal(first.FirstSeenAt) || failed.Summary != listing.Summary {
+		t.Errorf("article after a failed fetch = %+v", failed)
+	}
+
+	site.setPage("/news/zelda", zeldaPage)
+	if err := s.AddArticles([]scraper.Article{listing}, scr); err != nil {
+		t.Fatalf("AddArticles() error = %v", err)
+	}
+	if n := site.requestCount("/news/zelda"); n != 3 {
+		t.Errorf("failed article fetched %d times in total, want 3", n)
+	}
+	if retried := stored(); retried.ListingHash == "" || !retried.FirstSeenAt.Equal(first.FirstSeenAt) {
+		t.Errorf("article after a retry = %+v", retried)
+	}
+}
+
+func TestSaveArticlesMergesStored(t *testing.T) {
+	t.Setenv("MONGO_URI", "")
+	s := NewStorage()
+
+	seen := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+	s.inMemoryArticles["zelda"] = ArticleWithContent{ID: "zelda", Content: zeldaStory, FirstSeenAt: seen, Aliases: []string{"legacy"}}
+
+	// Another ingest read the article before it was stored and failed to fetch its details
+	err := s.saveArticles([]ArticleWithContent{{ID: "zelda", Summary: "Updated", FirstSeenAt: seen.Add(time.Hour), Aliases: []string{"listing"}}})
+	if err != nil {
+		t.Fatalf("saveArticles() error = %v", err)
+	}
+
+	got := s.inMemoryArticles["zelda"]
+	if got.Content != zeldaStory || !got.FirstSeenAt.Equal(seen) || got.Summary != "Updated" ||
+		len(got.Aliases) != 2 || got.Aliases[0] != "legacy" || got.Aliases[1] != "listing" {
+		t.Errorf("merged article = %+v", got)
+	}
+}
+
+func TestSourceEvents(t *testing.T) {
+	t.Setenv("MONGO_URI", "")
+	s := NewStorage()
+
+	start := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < maxSourceEvents+10; i++ {
+		source := "Example"
+		if i%2 == 1 {
+			source = "Other"
+		}
+		event := scraper.DegradedEvent{Source: source, DetectedAt: start.Add(time.Duration(i) * time.Minute)}
+		if err := s.SaveSourceEvent(event); err != nil {
+			t.Fatalf("SaveSourceEvent() error = %v", err)
+		}
+	}
+
+	events, err := s.GetSourceEvents("", 0)
+	if err != nil {
+		t.Fatalf("GetSourceEvents() error = %v", err)
+	}
+	if len(events) != maxSourceEvents {
+		t.Fatalf("kept %d events, want %d", len(events), maxSourceEvents)
+	}
+	if newest := start.Add((maxSourceEvents + 9) * time.Minute); !events[0].DetectedAt.Equal(newest) || events[0].Type != SourceEventDegraded {
+		t.Errorf("newest event = %+v", events[0])
+	}
+
+	events, err = s.GetSourceEvents("Example", 3)
+	if err != nil {
+		t.Fatalf("GetSourceEvents() error = %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events of Example, want 3", len(events))
+	}
+	for _, event := range events {
+		if event.Source != "Example" {
+			t.Errorf("event of %s returned for Example", event.Source)
+		}
+	}
+}
@@ -0,0 +1,425 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"game-news/textutil"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Story is a cluster of articles from one or more sources covering the same event
+type Story struct {
+	ID               string    `bson:"id"`
+	Headline         string    `bson:"headline"`
+	Sources          []string  `bson:"sources"`
+	ArticleCount     int       `bson:"article_count"`
+	FirstPublishedAt time.Time `bson:"first_published_at"`
+	LastPublishedAt  time.Time `bson:"last_published_at"`
+	UpdatedAt        time.Time `bson:"updated_at"`
+}
+
+// storyWindow is how far apart two articles can be published and still be
+// clustered into the same story
+const storyWindow = 48 * time.Hour
+
+// Similarity thresholds for clustering. Titles are short, so a high overlap
+// of title terms alone is enough; otherwise title and summary together must
+// share several terms.
+const (
+	storyTitleJaccard   = 0.4
+	storyTitleMinShared = 2
+	storyTextJaccard    = 0.25
+	storyTextMinShared  = 4
+)
+
+// storyTerms holds the terms of an article used for clustering
+type storyTerms struct {
+	title map[string]bool
+	text  map[string]bool
+}
+
+func newStoryTerms(article ArticleWithContent) storyTerms {
+	return storyTerms{
+		title: textutil.Terms(article.Title),
+		text:  textutil.Terms(article.Title + "\n" + article.Summary),
+	}
+}
+
+// similarity scores how likely two articles cover the same event, zero when
+// they are not similar enough to be clustered
+func (t storyTerms) similarity(other storyTerms) float64 {
+	score := 0.0
+	if textutil.Overlap(t.title, other.title) >= storyTitleMinShared {
+		if jaccard := textutil.Jaccard(t.title, other.title); jaccard >= storyTitleJaccard {
+			score = jaccard
+		}
+	}
+	if textutil.Overlap(t.text, other.text) >= storyTextMinShared {
+		if jaccard := textutil.Jaccard(t.text, other.text); jaccard >= storyTextJaccard && jaccard > score {
+			score = jaccard
+		}
+	}
+	return score
+}
+
+// assignStories clusters new or changed articles into stories. Each article
+// joins the story of its near-duplicate primary, or of the most similar
+// article published within storyWindow; when that article has no story yet,
+// a new story is started for both. Articles that already belong to a story
+// keep it. Matched stored articles and the touched stories are saved here;
+// the batch articles get their StoryID set and are saved by the caller.
+func (s *Storage) assignStories(articles []ArticleWithContent, recent []ArticleWithContent) error {
+	pool := make([]ArticleWithContent, 0, len(recent)+len(articles))
+	batch := make(map[string]int, len(articles))
+	for i, article := range articles {
+		batch[article.ID] = i
+	}
+	for _, article := range recent {
+		if _, inBatch := batch[article.ID]; !inBatch {
+			pool = append(pool, article)
+		}
+	}
+
+	terms := make(map[string]storyTerms)
+	termsOf := func(article ArticleWithContent) storyTerms {
+		t, ok := terms[article.ID]
+		if !ok {
+			t = newStoryTerms(article)
+			terms[article.ID] = t
+		}
+		return t
+	}
+
+	// Stored articles that join a story, keyed by article ID
+	storedUpdates := make(map[string]string)
+	members := make(map[string][]ArticleWithContent)
+
+	for i := range articles {
+		article := &articles[i]
+		if article.StoryID != "" {
+			pool = append(pool, *article)
+			continue
+		}
+
+		match := -1
+		if article.DuplicateOf != "" {
+			for j, candidate := range pool {
+				if candidate.ID == article.DuplicateOf {
+					match = j
+					break
+				}
+			}
+		}
+		if match < 0 {
+			best := 0.0
+			for j, candidate := range pool {
+				gap := candidate.PublishedAt.Sub(article.PublishedAt)
+				if gap > storyWindow || gap < -storyWindow {
+					continue
+				}
+				if score := termsOf(*article).similarity(termsOf(candidate)); score > best {
+					best = score
+					match = j
+				}
+			}
+		}
+
+		if match >= 0 {
+			candidate := &pool[match]
+			if candidate.StoryID == "" {
+				// The matched article founds the story
+				candidate.StoryID = candidate.ID
+				if j, inBatch := batch[candidate.ID]; inBatch {
+					articles[j].StoryID = candidate.StoryID
+				} else {
+					storedUpdates[candidate.ID] = candidate.StoryID
+				}
+				members[candidate.StoryID] = append(members[candidate.StoryID], *candidate)
+			}
+			article.StoryID = candidate.StoryID
+			members[article.StoryID] = append(members[article.StoryID], *article)
+		}
+
+		pool = append(pool, *article)
+	}
+
+	if len(members) == 0 {
+		return nil
+	}
+
+	if err := s.setStoryIDs(storedUpdates); err != nil {
+		return err
+	}
+	return s.updateStories(members)
+}
+
+// setStoryIDs sets the story of stored articles
+func (s *Storage) setStoryIDs(storyIDs map[string]string) error {
+	if len(storyIDs) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// If using in-memory storage
+	if s.useInMemory {
+		for id, storyID := range storyIDs {
+			if article, exists := s.inMemoryArticles[id]; exists {
+				article.StoryID = storyID
+				s.inMemoryArticles[id] = article
+			}
+		}
+		return nil
+	}
+
+	// Use MongoDB
+	ctx := context.Background()
+
+	models := make([]mongo.WriteModel, 0, len(storyIDs))
+	for id, storyID := range storyIDs {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"id": id}).
+			SetUpdate(bson.M{"$set": bson.M{"story_id": storyID}}))
+	}
+
+	_, err := s.articles.BulkWrite(ctx, models)
+	return err
+}
+
+// updateStories adds newly clustered articles to their stories, creating the
+// stories that do not exist yet
+func (s *Storage) updateStories(members map[string][]ArticleWithContent) error {
+	ids := make([]string, 0, len(members))
+	for id := range members {
+		ids = append(ids, id)
+	}
+
+	stories, err := s.getStoriesByID(ids)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	updated := make([]Story, 0, len(members))
+	for id, articles := range members {
+		story, exists := stories[id]
+		if !exists {
+			story = Story{ID: id}
+		}
+
+		for _, article := range articles {
+			story.ArticleCount++
+			if !containsString(story.Sources, article.Source) {
+				story.Sources = append(story.Sources, article.Source)
+			}
+			// The earliest article's title is the headline
+			if story.FirstPublishedAt.IsZero() || article.PublishedAt.Before(story.FirstPublishedAt) {
+				story.FirstPublishedAt = article.PublishedAt
+				story.Headline = article.Title
+			}
+			if article.PublishedAt.After(story.LastPublishedAt) {
+				story.LastPublishedAt = article.PublishedAt
+			}
+		}
+		sort.Strings(story.Sources)
+		story.UpdatedAt = now
+
+		updated = append(updated, story)
+	}
+
+	return s.saveStories(updated)
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// getStoriesByID returns the stored stories among the given IDs, keyed by ID
+func (s *Storage) getStoriesByID(ids []string) (map[string]Story, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	found := make(map[string]Story)
+
+	// If using in-memory storage
+	if s.useInMemory {
+		for _, id := range ids {
+			if story, exists := s.inMemoryStories[id]; exists {
+				found[id] = story
+			}
+		}
+		return found, nil
+	}
+
+	// Use MongoDB
+	ctx := context.Background()
+
+	cursor, err := s.stories.Find(ctx, bson.M{"id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var stories []Story
+	if err = cursor.All(ctx, &stories); err != nil {
+		return nil, err
+	}
+
+	for _, story := range stories {
+		found[story.ID] = story
+	}
+
+	return found, nil
+}
+
+// saveStories upserts stories
+func (s *Storage) saveStories(stories []Story) error {
+	if len(stories) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// If using in-memory storage
+	if s.useInMemory {
+		for _, story := range stories {
+			s.inMemoryStories[story.ID] = story
+		}
+		return nil
+	}
+
+	// Use MongoDB
+	ctx := context.Background()
+
+	models := make([]mongo.WriteModel, 0, len(stories))
+	for _, story := range stories {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"id": story.ID}).
+			SetUpdate(bson.M{"$set": story}).
+			SetUpsert(true))
+	}
+
+	_, err := s.stories.BulkWrite(ctx, models)
+	return err
+}
+
+// GetStories returns the most recently updated stories, newest first
+func (s *Storage) GetStories(limit int) ([]Story, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// If using in-memory storage
+	if s.useInMemory {
+		stories := make([]Story, 0, len(s.inMemoryStories))
+		for _, story := range s.inMemoryStories {
+			stories = append(stories, story)
+		}
+
+		sort.Slice(stories, func(i, j int) bool {
+			return stories[i].LastPublishedAt.After(stories[j].LastPublishedAt)
+		})
+		if limit > 0 && len(stories) > limit {
+			stories = stories[:limit]
+		}
+
+		return stories, nil
+	}
+
+	// Use MongoDB
+	ctx := context.Background()
+
+	findOptions := options.Find().SetSort(bson.D{{"last_published_at", -1}})
+	if limit > 0 {
+		findOptions.SetLimit(int64(limit))
+	}
+
+	cursor, err := s.stories.Find(ctx, bson.M{}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	stories := make([]Story, 0)
+	if err = cursor.All(ctx, &stories); err != nil {
+		return nil, err
+	}
+
+	return stories, nil
+}
+
+// GetStoryByID returns a story by ID
+func (s *Storage) GetStoryByID(id string) (Story, bool, error) {
+	stories, err := s.getStoriesByID([]string{id})
+	if err != nil {
+		return Story{}, false, err
+	}
+
+	story, exists := stories[id]
+	return story, exists, nil
+}
+
+// GetStoryArticles returns the member articles of the given stories without
+// content, keyed by story ID and ordered oldest first
+func (s *Storage) GetStoryArticles(storyIDs []string) (map[string][]ArticleWithContent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	members := make(map[string][]ArticleWithContent)
+	if len(storyIDs) == 0 {
+		return members, nil
+	}
+
+	var articles []ArticleWithContent
+
+	// If using in-memory storage
+	if s.useInMemory {
+		wanted := make(map[string]bool, len(storyIDs))
+		for _, id := range storyIDs {
+			wanted[id] = true
+		}
+
+		for _, article := range s.inMemoryArticles {
+			if wanted[article.StoryID] {
+				article.Content = ""
+				articles = append(articles, article)
+			}
+		}
+	} else {
+		// Use MongoDB
+		ctx := context.Background()
+
+		findOptions := options.Find().SetProjection(bson.M{"content": 0})
+		cursor, err := s.articles.Find(ctx, bson.M{"story_id": bson.M{"$in": storyIDs}}, findOptions)
+		if err != nil {
+			return nil, err
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &articles); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, article := range articles {
+		members[article.StoryID] = append(members[article.StoryID], article)
+	}
+	for _, group := range members {
+		sort.Slice(group, func(i, j int) bool {
+			return group[i].PublishedAt.Before(group[j].PublishedAt)
+		})
+	}
+
+	return members, nil
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStorySimilarity(t *testing.T) {
+	tests := []struct {
+		name        string
+		a, b        ArticleWithContent
+		wantCluster bool
+	}{
+		{
+			name:        "same headline reworded",
+			a:           ArticleWithContent{Title: "Nintendo announces Zelda sequel for Switch 2"},
+			b:           ArticleWithContent{Title: "Zelda sequel announced for Switch 2 by Nintendo"},
+			wantCluster: true,
+		},
+		{
+			name: "different headlines, shared summary",
+			a: ArticleWithContent{Title: "Nintendo Direct: everything announced",
+				Summary: "A new Zelda game launches on Switch 2 in March with co-op mode and a playable Zelda"},
+			b: ArticleWithContent{Title: "Zelda is finally playable",
+				Summary: "Nintendo confirmed the Zelda game for Switch 2 arrives in March and adds a co-op mode"},
+			wantCluster: true,
+		},
+		{
+			name:        "chinese headlines",
+			a:           ArticleWithContent{Title: "任天堂公布塞尔达传说新作 明年三月发售"},
+			b:           ArticleWithContent{Title: "塞尔达传说新作公布：明年三月登陆Switch 2"},
+			wantCluster: true,
+		},
+		{
+			name:        "same franchise, different event",
+			a:           ArticleWithContent{Title: "Nintendo announces Zelda sequel for Switch 2"},
+			b:           ArticleWithContent{Title: "Zelda movie casting revealed by Sony Pictures"},
+			wantCluster: false,
+		},
+		{
+			name:        "one shared word is not enough",
+			a:           ArticleWithContent{Title: "Zelda"},
+			b:           ArticleWithContent{Title: "Zelda"},
+			wantCluster: false,
+		},
+		{
+			name:        "unrelated",
+			a:           ArticleWithContent{Title: "Counter-Strike 2 update reworks the economy"},
+			b:           ArticleWithContent{Title: "Nintendo announces Zelda sequel for Switch 2"},
+			wantCluster: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := newStoryTerms(tt.a), newStoryTerms(tt.b)
+			score := a.similarity(b)
+			if (score > 0) != tt.wantCluster {
+				t.Errorf("similarity() = %v, want clustered %v", score, tt.wantCluster)
+			}
+			if reverse := b.similarity(a); reverse != score {
+				t.Errorf("similarity() is not symmetric: %v and %v", score, reverse)
+			}
+		})
+	}
+}
+
+func TestAssignStories(t *testing.T) {
+	t.Setenv("MONGO_URI", "")
+	s := NewStorage()
+
+	base := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+	stored := []ArticleWithContent{
+		{ID: "ign", Source: "IGN", Title: "Nintendo announces Zelda sequel for Switch 2", PublishedAt: base},
+		{ID: "old", Source: "Polygon", Title: "Nintendo announces Zelda sequel for Switch 2", PublishedAt: base.Add(-72 * time.Hour)},
+	}
+	for _, article := range stored {
+		s.inMemoryArticles[article.ID] = article
+	}
+
+	batch := []ArticleWithContent{
+		{ID: "gamespot", Source: "GameSpot", Title: "Zelda sequel announced for Switch 2 by Nintendo", PublishedAt: base.Add(2 * time.Hour)},
+		{ID: "cs2", Source: "PC Gamer", Title: "Counter-Strike 2 update reworks the economy", PublishedAt: base.Add(time.Hour)},
+		{ID: "reprint", Source: "Kotaku", Title: "Big Nintendo news today", PublishedAt: base.Add(3 * time.Hour), DuplicateOf: "gamespot"},
+	}
+	if err := s.assignStories(batch, stored); err != nil {
+		t.Fatalf("assignStories() error = %v", err)
+	}
+
+	storyIDs := map[string]string{}
+	for _, article := range batch {
+		storyIDs[article.ID] = article.StoryID
+	}
+	storyIDs["ign"] = s.inMemoryArticles["ign"].StoryID
+	storyIDs["old"] = s.inMemoryArticles["old"].StoryID
+
+	want := map[string]string{"ign": "ign", "gamespot": "ign", "reprint": "ign", "cs2": "", "old": ""}
+	if !reflect.DeepEqual(storyIDs, want) {
+		t.Errorf("story IDs = %v, want %v", storyIDs, want)
+	}
+
+	story, ok := s.inMemoryStories["ign"]
+	if !ok {
+		t.Fatal("story was not saved")
+	}
+	if story.ArticleCount != 3 || story.Headline != stored[0].Title ||
+		!reflect.DeepEqual(story.Sources, []string{"GameSpot", "IGN", "Kotaku"}) ||
+		!story.FirstPublishedAt.Equal(base) || !story.LastPublishedAt.Equal(base.Add(3*time.Hour)) {
+		t.Errorf("story = %+v", story)
+	}
+}
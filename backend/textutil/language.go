@@ -0,0 +1,184 @@
+package textutil
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/width"
+)
+
+// 语言代码，使用 ISO 639-1
+const (
+	LanguageEnglish  = "en"
+	LanguageChinese  = "zh"
+	LanguageJapanese = "ja"
+	LanguageKorean   = "ko"
+)
+
+// minEnglishStopWordRatio 是长文本被判断为英文所需的最低虚词比例，英文正文通常在三成以上
+const minEnglishStopWordRatio = 0.05
+
+// shortTextWords 是不检查虚词比例的短文本词数，标题往往不含虚词
+const shortTextWords = 8
+
+// DetectLanguage 按文字种类和常见虚词判断文本的语言，无法判断时返回空字符串
+//
+// 只区分来源涉及的语言：汉字、假名、谚文合计多于拉丁字母的三分之一时为东亚语言，
+// 其中含有一定比例假名的为日文，谚文多于汉字的为韩文，其余为中文（不区分简繁）；
+// 拉丁字母为主、且含有英文虚词的为英文。
+func DetectLanguage(text string) string {
+	var han, kana, hangul, latin int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+
+	// 一个汉字承载的信息大致相当于几个拉丁字母，中文正文中夹杂的英文名称不影响判断
+	cjk := han + kana + hangul
+	if cjk > 0 && cjk*3 >= latin {
+		switch {
+		case kana*10 >= cjk:
+			return LanguageJapanese
+		case hangul > han:
+			return LanguageKorean
+		default:
+			return LanguageChinese
+		}
+	}
+	if latin == 0 {
+		return ""
+	}
+
+	words, stops := 0, 0
+	for _, token := range Tokens(text) {
+		r, _ := utf8.DecodeRuneInString(token)
+		if !unicode.Is(unicode.Latin, r) {
+			continue
+		}
+		words++
+		if stopWords[token] {
+			stops++
+		}
+	}
+	if words <= shortTextWords || float64(stops) >= float64(words)*minEnglishStopWordRatio {
+		return LanguageEnglish
+	}
+	return ""
+}
+
+// SearchTerms 将查询切分为搜索用的词，去掉重复，保持首次出现的顺序
+//
+// 全角字母数字先转换为半角。英文等以空格分词的文字按词切分，去掉虚词和单个字母；
+// 汉字、假名等连续的文字按相邻两字切分，只有一个字时保留单字。
+// 文本包含查询的每个词即为匹配，见 MatchesTerms。
+func SearchTerms(text string) []string {
+	terms := make([]string, 0)
+	seen := make(map[string]bool)
+	add := func(term string) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+
+	var word strings.Builder
+	run := make([]rune, 0)
+
+	flushWord := func() {
+		if word.Len() == 0 {
+			return
+		}
+		token := word.String()
+		word.Reset()
+		if stopWords[token] || (utf8.RuneCountInString(token) < 2 && !isDigits(token)) {
+			return
+		}
+		add(token)
+	}
+	flushRun := func() {
+		switch len(run) {
+		case 0:
+			return
+		case 1:
+			add(string(run))
+		default:
+			for i := 0; i+1 < len(run); i++ {
+				add(string(run[i : i+2]))
+			}
+		}
+		run = run[:0]
+	}
+
+	for _, r := range strings.ToLower(width.Fold.String(text)) {
+		switch {
+		case isCJK(r):
+			flushWord()
+			run = append(run, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushRun()
+			word.WriteRune(r)
+		default:
+			flushWord()
+			flushRun()
+		}
+	}
+	flushWord()
+	flushRun()
+
+	return terms
+}
+
+// wordStartPattern 匹配词首之前的位置：文本开头、非字母数字，或不以空格分词的文字
+const wordStartPattern = `(?:^|[^\p{L}\p{N}]|[\p{Han}\p{Hiragana}\p{Katakana}\p{Hangul}])`
+
+// MatchesTerms 判断文本是否包含 SearchTerms 切分出的每个查询词
+//
+// 以空格分词的词匹配文本中以它开头的词，"retr" 能匹配 "Retro"；汉字、假名等
+// 出现在文本的任意位置即为匹配，只有一个字的查询也一样。数据库查询使用 TermPattern，
+// 两者的规则相同。
+func MatchesTerms(text string, terms []string) bool {
+	text = strings.ToLower(width.Fold.String(text))
+	var words []string
+
+	for _, term := range terms {
+		if r, _ := utf8.DecodeRuneInString(term); isCJK(r) {
+			if !strings.Contains(text, term) {
+				return false
+			}
+			continue
+		}
+
+		if words == nil {
+			words = Tokens(text)
+		}
+		found := false
+		for _, word := range words {
+			if strings.HasPrefix(word, term) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// TermPattern 返回与 MatchesTerms 规则相同的正则表达式，用于不区分大小写的数据库查询
+func TermPattern(term string) string {
+	if r, _ := utf8.DecodeRuneInString(term); isCJK(r) {
+		return regexp.QuoteMeta(term)
+	}
+	return wordStartPattern + regexp.QuoteMeta(term)
+}
@@ -0,0 +1,113 @@
+package textutil
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"english title", "Nintendo Announces New Zelda Game", LanguageEnglish},
+		{"english body", "The studio said the game will be released on Switch and PC later this year, and that a demo is available now.", LanguageEnglish},
+		{"simplified chinese", "任天堂公布塞尔达新作，将于明年发售", LanguageChinese},
+		{"traditional chinese", "任天堂公佈薩爾達新作，將於明年發售", LanguageChinese},
+		{"chinese with english names", "《Elden Ring》DLC 销量突破500万份，FromSoftware 表示感谢", LanguageChinese},
+		{"japanese", "任天堂がゼルダの新作を発表しました", LanguageJapanese},
+		{"katakana only", "ゼルダ", LanguageJapanese},
+		{"korean", "닌텐도, 젤다 신작 발표", LanguageKorean},
+		{"korean with hanja", "任天堂 신작 게임 발표 예정", LanguageKorean},
+		{"long non-english latin", "Der Entwickler hat heute bestätigt, dass das Spiel noch dieses Jahr für Konsolen und Computer erscheinen wird", ""},
+		{"digits only", "2024 123", ""},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectLanguage(tt.text); got != tt.want {
+				t.Errorf("DetectLanguage(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"english words", "The Legend of Zelda", []string{"legend", "zelda"}},
+		{"single letters dropped, digits kept", "a b 2 GTA 6", []string{"2", "gta", "6"}},
+		{"duplicates removed", "zelda Zelda ZELDA", []string{"zelda"}},
+		{"chinese pairs", "塞尔达新作", []string{"塞尔", "尔达", "达新", "新作"}},
+		{"single chinese character", "游", []string{"游"}},
+		{"mixed", "《塞尔达》Switch 2发售", []string{"塞尔", "尔达", "switch", "2", "发售"}},
+		{"punctuation splits runs", "游戏，新闻", []string{"游戏", "新闻"}},
+		{"full-width folded", "ＰＳ５ Ｐｒｏ", []string{"ps5", "pro"}},
+		{"kana", "ゼルダ", []string{"ゼル", "ルダ"}},
+		{"stop words only", "the of and", []string{}},
+		{"empty", "", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SearchTerms(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SearchTerms(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+// searchCases 同时用于检查 MatchesTerms 与 TermPattern，两者的结果必须一致
+var searchCases = []struct {
+	name  string
+	text  string
+	query string
+	want  bool
+}{
+	{"whole word", "Zelda sequel announced", "zelda", true},
+	{"case insensitive", "ZELDA sequel", "Zelda", true},
+	{"word prefix", "Retro Studios hiring", "retr", true},
+	{"not inside a word", "Metroid Prime", "troid", false},
+	{"every term required", "Zelda sequel announced", "zelda delayed", false},
+	{"terms in any order", "Zelda sequel announced", "announced zelda", true},
+	{"english after chinese", "任天堂Switch新机", "switch", true},
+	{"chinese pair", "塞尔达新作公布", "新作", true},
+	{"chinese phrase", "塞尔达新作公布", "塞尔达新作", true},
+	{"chinese pair missing", "塞尔达新作公布", "新闻", false},
+	{"single chinese character", "游民星空新闻", "游", true},
+	{"single chinese character missing", "机核新闻", "游", false},
+	{"single kana", "ゼルダの伝説", "の", true},
+	{"number prefix", "GTA 6 trailer", "6", true},
+}
+
+func TestMatchesTerms(t *testing.T) {
+	for _, tt := range searchCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchesTerms(tt.text, SearchTerms(tt.query)); got != tt.want {
+				t.Errorf("MatchesTerms(%q, %q) = %v, want %v", tt.text, tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTermPattern(t *testing.T) {
+	for _, tt := range searchCases {
+		t.Run(tt.name, func(t *testing.T) {
+			got := true
+			for _, term := range SearchTerms(tt.query) {
+				if !regexp.MustCompile("(?i)" + TermPattern(term)).MatchString(tt.text) {
+					got = false
+				}
+			}
+			if got != tt.want {
+				t.Errorf("TermPattern matches %q for %q = %v, want %v", tt.text, tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+// Package textutil 提供文本处理工具，例如分词、语言检测与近似重复检测用的指纹。
+package textutil
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+// NearDuplicateDistance 是两个指纹被视为近似重复时允许的最大汉明距离
+//
+// 新闻长度的文本中，同一通稿的转载通常相差不到10位，而无关文本平均相差约32位。
+const NearDuplicateDistance = 10
+
+// minFeatures 是计算指纹所需的最少特征数，过短的文本指纹不可靠
+const minFeatures = 16
+
+// Tokens 将文本切分为小写的词，汉字、假名等没有空格分隔的文字逐字切分
+func Tokens(text string) []string {
+	tokens := make([]string, 0)
+	var word strings.Builder
+
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case isCJK(r):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+// isCJK 判断字符是否属于不使用空格分词的文字
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// SimHash 计算文本的64位 SimHash 指纹
+//
+// 以相邻两个词组成的词组作为特征，措辞略有差异的转载文章得到的指纹只相差少数几位。
+// 文本过短时返回 0，表示没有可用的指纹。
+func SimHash(text string) uint64 {
+	tokens := Tokens(text)
+	if len(tokens) < 2 {
+		return 0
+	}
+
+	weights := make(map[string]int)
+	for i := 0; i+1 < len(tokens); i++ {
+		weights[tokens[i]+" "+tokens[i+1]]++
+	}
+	if len(weights) < minFeatures {
+		return 0
+	}
+
+	var vector [64]int
+	for feature, weight := range weights {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		hash := h.Sum64()
+
+		for bit := 0; bit < 64; bit++ {
+			if hash&(1<<bit) != 0 {
+				vector[bit] += weight
+			} else {
+				vector[bit] -= weight
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for bit := 0; bit < 64; bit++ {
+		if vector[bit] > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return fingerprint
+}
+
+// HammingDistance 返回两个指纹不同的位数
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// NearDuplicate 判断两个指纹对应的文本是否近似重复，没有指纹（为0）时返回 false
+func NearDuplicate(a, b uint64) bool {
+	return a != 0 && b != 0 && HammingDistance(a, b) <= NearDuplicateDistance
+}
@@ -0,0 +1,101 @@
+package textutil
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	wireStory = "Nintendo has announced that the next Legend of Zelda game will launch on Switch 2 in March next year. " +
+		"The publisher showed a first trailer during its Direct presentation on Tuesday, revealing a new open world, " +
+		"a playable Zelda and a co-op mode for two players. Pre-orders open today in North America and Europe, " +
+		"with a collector's edition that includes an art book and a steelbook case."
+	wireReprint = "Nintendo has announced that the next Legend of Zelda game will launch on Switch 2 in March next year. " +
+		"The publisher showed the first trailer during its Direct presentation on Tuesday, revealing a new open world, " +
+		"a playable Zelda and a co-op mode for two players. Pre-orders open today in North America and Europe, " +
+		"with a collector's edition that includes an art book and a steelbook case. Source: Nintendo."
+	unrelatedStory = "Valve has released a major update for Counter-Strike 2 that reworks the economy of competitive matches. " +
+		"Players now earn more money after losing rounds, and several rifles received price changes. The patch also " +
+		"fixes a number of crashes on Linux, improves the performance of smoke grenades and adds two community maps " +
+		"to the active duty pool for the next season."
+	chineseStory = "任天堂宣布《塞尔达传说》系列新作将于明年三月登陆Switch 2平台，官方在周二的直面会上公布了首支预告片，" +
+		"展示了全新的开放世界、可操作的塞尔达公主以及双人合作模式。北美和欧洲地区今天开启预购，限定版附赠设定集和铁盒。"
+	chineseReprint = "任天堂宣布《塞尔达传说》系列新作将于明年三月登陆Switch 2平台，官方在周二的任天堂直面会上公布了首支预告片，" +
+		"展示了全新的开放世界、可操作的塞尔达公主以及双人合作模式。北美和欧洲地区今天开启预购，限定版附赠设定集和铁盒。（来源：任天堂）"
+)
+
+func TestTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Zelda: Tears of the Kingdom", []string{"zelda", "tears", "of", "the", "kingdom"}},
+		{"Switch 2发售", []string{"switch", "2", "发", "售"}},
+		{"PS5-Pro", []string{"ps5", "pro"}},
+		{"", []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := Tokens(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokens(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSimHash(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{"identical", wireStory, wireStory, true},
+		{"reprint with small edits", wireStory, wireReprint, true},
+		{"case and punctuation ignored", wireStory, strings.ToUpper(strings.ReplaceAll(wireStory, ",", " -")), true},
+		{"unrelated story", wireStory, unrelatedStory, false},
+		{"chinese reprint", chineseStory, chineseReprint, true},
+		{"chinese and english", chineseStory, wireStory, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := SimHash(tt.a), SimHash(tt.b)
+			if a == 0 || b == 0 {
+				t.Fatalf("SimHash() = %x, %x, want fingerprints", a, b)
+			}
+			if got := NearDuplicate(a, b); got != tt.want {
+				t.Errorf("NearDuplicate() = %v with distance %d, want %v", got, HammingDistance(a, b), tt.want)
+			}
+		})
+	}
+}
+
+func TestSimHashShortText(t *testing.T) {
+	for _, text := range []string{"", "Zelda", "Nintendo announces a new Zelda game for Switch 2", "任天堂公布新作"} {
+		if got := SimHash(text); got != 0 {
+			t.Errorf("SimHash(%q) = %x, want 0", text, got)
+		}
+	}
+}
+
+func TestNearDuplicate(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b uint64
+		want bool
+	}{
+		{"equal", 0xdeadbeef, 0xdeadbeef, true},
+		{"within distance", 0xffff, 0xffff ^ 0x3ff, true},
+		{"beyond distance", 0xffff, 0xffff ^ 0x7ff, false},
+		{"missing fingerprint", 0, 0x1, false},
+		{"both missing", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NearDuplicate(tt.a, tt.b); got != tt.want {
+				t.Errorf("NearDuplicate(%x, %x) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package textutil
+
+import "unicode/utf8"
+
+// stopWords 是比较相似度时忽略的常见英文虚词
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "has": true, "have": true,
+	"in": true, "is": true, "it": true, "its": true, "of": true, "on": true,
+	"or": true, "that": true, "the": true, "this": true, "to": true, "was": true,
+	"were": true, "will": true, "with": true, "new": true, "after": true,
+	"how": true, "what": true, "why": true, "when": true, "you": true, "your": true,
+	"about": true, "into": true, "up": true, "out": true, "more": true, "than": true,
+}
+
+// Terms 返回文本中有意义的词集合，用于比较两段文本的相似度
+//
+// 英文去掉虚词和单个字母；连续的汉字、假名等按相邻两字组成词，单字本身区分度太低。
+func Terms(text string) map[string]bool {
+	terms := make(map[string]bool)
+
+	var previous string
+	for _, token := range Tokens(text) {
+		r, _ := utf8.DecodeRuneInString(token)
+		if isCJK(r) {
+			if previous != "" {
+				terms[previous+token] = true
+			}
+			previous = token
+			continue
+		}
+		previous = ""
+
+		if stopWords[token] || (utf8.RuneCountInString(token) < 2 && !isDigits(token)) {
+			continue
+		}
+		terms[token] = true
+	}
+
+	return terms
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// Overlap 返回两个词集合的共同词数
+func Overlap(a, b map[string]bool) int {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+
+	shared := 0
+	for term := range a {
+		if b[term] {
+			shared++
+		}
+	}
+	return shared
+}
+
+// Jaccard 返回两个词集合的 Jaccard 相似度（交集大小除以并集大小）
+func Jaccard(a, b map[string]bool) float64 {
+	shared := Overlap(a, b)
+	union := len(a) + len(b) - shared
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}